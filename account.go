@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/charmbracelet/ssh"
+)
+
+const guestAccount = "guest"
+
+// accountID identifica al jugador por su clave pública. Las sesiones sin
+// clave (contraseña) se tratan como invitados.
+func accountID(s ssh.Session) string {
+	key := s.PublicKey()
+	if key == nil {
+		return guestAccount
+	}
+	sum := sha256.Sum256(key.Marshal())
+	return hex.EncodeToString(sum[:8])
+}
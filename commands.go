@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"ssh-dungeon-crawler/game"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// commandMiddleware atiende los comandos exec que no abren el juego, por
+// ejemplo `ssh -p 2222 host recordings`.
+func commandMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			cmd := s.Command()
+			if len(cmd) == 0 {
+				next(s)
+				return
+			}
+
+			switch cmd[0] {
+			case "recordings":
+				runListRecordings(s)
+			case "recording":
+				if len(cmd) != 2 {
+					wish.Fatalln(s, "usage: recording <name>")
+					return
+				}
+				runDownloadRecording(s, cmd[1])
+			default:
+				next(s)
+			}
+		}
+	}
+}
+
+func runListRecordings(s ssh.Session) {
+	account := accountID(s)
+	if account == guestAccount {
+		wish.Fatalln(s, "Recordings are only available when connecting with a public key")
+		return
+	}
+
+	recordings, err := listRecordings(account)
+	if err != nil {
+		log.Printf("Failed to list recordings for %s: %v", account, err)
+		wish.Fatalln(s, "Error: could not list recordings")
+		return
+	}
+	if len(recordings) == 0 {
+		wish.Println(s, "No recordings yet")
+		return
+	}
+
+	for _, rec := range recordings {
+		wish.Printf(s, "%s\t%s\t%d bytes\n", rec.Name, rec.ModTime.Format("2006-01-02 15:04:05"), rec.Size)
+	}
+}
+
+func runDownloadRecording(s ssh.Session, name string) {
+	account := accountID(s)
+	if account == guestAccount {
+		wish.Fatalln(s, "Recordings are only available when connecting with a public key")
+		return
+	}
+
+	file, err := openRecording(account, name)
+	if err != nil {
+		wish.Fatalln(s, "Error: recording not found")
+		return
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(s, file); err != nil {
+		log.Printf("Failed to send recording %s to %s: %v", name, account, err)
+	}
+}
+
+// editorAllowed indica si la cuenta puede abrir el editor de niveles. El
+// editor escribe en data/layouts del servidor, así que sólo pueden usarlo
+// las cuentas listadas en EDITOR_ACCOUNTS, separadas por comas.
+func editorAllowed(account string) bool {
+	if account == guestAccount {
+		return false
+	}
+	for _, allowed := range strings.Split(os.Getenv("EDITOR_ACCOUNTS"), ",") {
+		if strings.TrimSpace(allowed) == account {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGameCommand interpreta el comando con el que se abre el juego:
+// `test-combat`, `play [--seed N]` o `edit <nombre>`. Los comandos y
+// argumentos desconocidos se ignoran y abren el juego normal.
+func parseGameCommand(cmd []string) (game.Options, error) {
+	opts := game.Options{StartState: game.StateLoading}
+	if len(cmd) == 0 {
+		return opts, nil
+	}
+
+	switch cmd[0] {
+	case "test-combat":
+		opts.StartState = game.StateCombat
+	case "edit":
+		if len(cmd) != 2 {
+			return opts, fmt.Errorf("usage: edit <name>")
+		}
+		opts.StartState = game.StateEditor
+		opts.Layout = cmd[1]
+	case "play":
+		args := cmd[1:]
+		for i := 0; i < len(args); i++ {
+			name, value, hasValue := strings.Cut(args[i], "=")
+			if name != "--seed" && name != "-seed" {
+				continue
+			}
+			if !hasValue {
+				if i+1 == len(args) {
+					return opts, fmt.Errorf("usage: play [--seed N]")
+				}
+				i++
+				value = args[i]
+			}
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("usage: play [--seed N]: invalid seed %q", value)
+			}
+			opts.HasSeed = true
+			opts.Seed = seed
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestEditorAllowed(t *testing.T) {
+	t.Setenv("EDITOR_ACCOUNTS", "0123456789abcdef, fedcba9876543210,guest")
+	for _, tc := range []struct {
+		account string
+		want    bool
+	}{
+		{guestAccount, false},
+		{"0123456789abcdef", true},
+		{"fedcba9876543210", true},
+		{"00000000deadbeef", false},
+		{"", false},
+	} {
+		if got := editorAllowed(tc.account); got != tc.want {
+			t.Errorf("editorAllowed(%q) = %v, want %v", tc.account, got, tc.want)
+		}
+	}
+}
@@ -3,10 +3,22 @@ package game
 import (
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish/bubbletea"
 )
 
-func CreateTeaProgram(s ssh.Session, startState GameState) (tea.Model, []tea.ProgramOption) {
+func CreateTeaProgram(s ssh.Session, opts Options) (tea.Model, []tea.ProgramOption) {
+	renderer := lipgloss.DefaultRenderer()
+	if s != nil {
+		renderer = bubbletea.MakeRenderer(s)
+	}
+	return CreateTeaProgramWithRenderer(renderer, opts)
+}
+
+// CreateTeaProgramWithRenderer builds the game model for transports that are
+// not an ssh.Session, such as telnet or the local terminal.
+func CreateTeaProgramWithRenderer(renderer *lipgloss.Renderer, opts Options) (tea.Model, []tea.ProgramOption) {
 
 	prog := progress.New(
 		progress.WithGradient(string(orange), string(indigo)),
@@ -14,16 +26,20 @@ func CreateTeaProgram(s ssh.Session, startState GameState) (tea.Model, []tea.Pro
 	)
 
 	initialModel := model{
-		state:      startState,
+		state:      opts.StartState,
+		options:    opts,
 		menuCursor: 0,
 		progress:   prog,
-		styles:     newStyles(s),
+		styles:     newStyles(renderer),
 	}
 
-	if startState == StateCombat {
+	if opts.StartState == StateCombat {
 		initialModel.combat = newTestCombatState()
 		initialModel.player = *initialModel.combat.player.data
 	}
+	if opts.StartState == StateEditor {
+		initialModel.editor = newEditorState(opts.Layout)
+	}
 
 	return initialModel, []tea.ProgramOption{tea.WithAltScreen()}
 }
@@ -49,6 +65,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateGame(msg)
 	case StateCombat:
 		return m.updateCombat(msg)
+	case StateEditor:
+		return m.updateEditor(msg)
 	default:
 		return m, nil
 	}
@@ -65,9 +83,13 @@ func (m model) View() string {
 	case StateMenu:
 		return m.renderMenuView()
 	case StateGame:
+		m.styles = m.floorStyles()
 		return m.renderGameView()
 	case StateCombat:
+		m.styles = m.floorStyles()
 		return m.renderCombatView()
+	case StateEditor:
+		return m.renderEditorView()
 	default:
 		return "Unknown state"
 	}
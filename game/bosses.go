@@ -0,0 +1,164 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// BossTemplate is a boss from data/bosses.json. Bosses guard the StairsUp
+// room of boss floors and fight alongside their minions.
+type BossTemplate struct {
+	Foe
+	MinDepth int      `json:"minDepth"`
+	Intro    string   `json:"intro"`
+	Minions  []string `json:"minions"`
+}
+
+// BossPhase replaces the attacks of a foe once its HP falls below HPBelow
+// percent of its maximum.
+type BossPhase struct {
+	Name    string   `json:"name"`
+	HPBelow int      `json:"hpBelow"`
+	Attacks []Attack `json:"attacks"`
+}
+
+func isBossFloor(params FloorParams, floorNum int) bool {
+	if l, ok := LayoutTemplates[floorNum]; ok {
+		return l.hasBoss()
+	}
+	return params.BossEvery > 0 && (floorNum+1)%params.BossEvery == 0
+}
+
+// bossForDepth picks one of the bosses allowed at depth, or "" if none is.
+func bossForDepth(rng *rand.Rand, depth int) string {
+	var ids []string
+	for id, boss := range BossTemplates {
+		if boss.MinDepth <= depth {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Strings(ids)
+	return ids[rng.Intn(len(ids))]
+}
+
+// placeBossRoom turns a room next to the stairs into the boss room. The start,
+// the down stairs and the shop are kept; if no other neighbor is left, a new
+// room is dug next to the stairs instead. It returns false when there is no
+// room for the boss at all.
+func placeBossRoom(rng *rand.Rand, worldMap [][]*room, start, stairs image.Point) bool {
+	var candidates []image.Point
+	for dir, exit := range worldMap[stairs.Y][stairs.X].Exits {
+		if exit == nil {
+			continue
+		}
+		coord := stairs.Add(cardinalDirections[dir])
+		if t := worldMap[coord.Y][coord.X].Type; coord != start && t != StairsDown && t != Shop {
+			candidates = append(candidates, coord)
+		}
+	}
+
+	if len(candidates) == 0 {
+		for _, offset := range cardinalDirections {
+			coord := stairs.Add(offset)
+			if inBounds(worldMap, coord.X, coord.Y) && worldMap[coord.Y][coord.X] == nil {
+				candidates = append(candidates, coord)
+			}
+		}
+		if len(candidates) == 0 {
+			return false
+		}
+		coord := candidates[rng.Intn(len(candidates))]
+		worldMap[coord.Y][coord.X] = &room{Type: Boss}
+		connectRooms(worldMap, stairs, coord)
+		return true
+	}
+
+	coord := candidates[rng.Intn(len(candidates))]
+	bossRoom := worldMap[coord.Y][coord.X]
+	bossRoom.Type = Boss
+	bossRoom.Trap = ""
+	bossRoom.LootTable = ""
+	return true
+}
+
+// currentPhase returns the active phase index, or -1 while the foe is still
+// using its base attacks. When several thresholds are met the lowest wins.
+func (e *Foe) currentPhase() int {
+	phase := -1
+	for i, p := range e.Phases {
+		if e.MaxHP > 0 && e.HP*100 < p.HPBelow*e.MaxHP {
+			if phase == -1 || p.HPBelow < e.Phases[phase].HPBelow {
+				phase = i
+			}
+		}
+	}
+	return phase
+}
+
+func (e *Foe) currentAttacks() []Attack {
+	if phase := e.currentPhase(); phase >= 0 {
+		return e.Phases[phase].Attacks
+	}
+	return e.Attacks
+}
+
+// startBossCombat starts the fight against the floor boss. The combat screen
+// opens on the boss intro until the player presses enter.
+func (m model) startBossCombat(id string) model {
+	boss, ok := BossTemplates[id]
+	if !ok {
+		return m
+	}
+
+	foe := boss.Foe
+	enemies := []*Foe{&foe}
+	for _, minion := range boss.Minions {
+		enemies = append(enemies, newFoe(minion))
+	}
+
+	m = m.startCombat(enemies, false)
+	m.combat.boss = id
+	m.combat.showIntro = true
+	return m
+}
+
+func (m model) updateBossIntro(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() != "enter" {
+		return m, nil
+	}
+	m.combat.showIntro = false
+	if !m.combat.turnOrder[m.combat.turnIndex].IsPlayer() {
+		return m.startEnemyTurn()
+	}
+	return m, nil
+}
+
+func (m model) renderBossIntro() string {
+	boss := BossTemplates[m.combat.boss]
+
+	title := m.styles.Title.Render(fmt.Sprintf("☠  %s  ☠", boss.Name))
+	stats := []string{fmt.Sprintf("HP %d | STR %d | DEF %d | SPD %d", boss.MaxHP, boss.Strength, boss.Defense, boss.Speed)}
+	if len(boss.Minions) > 0 {
+		stats = append(stats, fmt.Sprintf("with %d minion(s)", len(boss.Minions)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Center,
+		title,
+		"",
+		boss.Intro,
+		"",
+		m.styles.Faint.Render(lipgloss.JoinVertical(lipgloss.Center, stats...)),
+		"",
+		m.styles.Help.Render("Press Enter to fight"),
+	)
+	panel := m.styles.BossPanel.Render(content)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, panel)
+}
@@ -0,0 +1,30 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestPlaceBossRoomKeepsShop(t *testing.T) {
+	// start - stairs - shop on one row, with free cells above and below.
+	start, stairs, shop := image.Point{X: 0, Y: 1}, image.Point{X: 1, Y: 1}, image.Point{X: 2, Y: 1}
+	for seed := range int64(20) {
+		worldMap := newWorldMap(3, 3)
+		worldMap[start.Y][start.X] = &room{}
+		worldMap[stairs.Y][stairs.X] = &room{Type: StairsUp}
+		worldMap[shop.Y][shop.X] = &room{Type: Shop, LootTable: "shop"}
+		connectRooms(worldMap, start, stairs)
+		connectRooms(worldMap, stairs, shop)
+
+		if !placeBossRoom(rand.New(rand.NewSource(seed)), worldMap, start, stairs) {
+			t.Fatalf("seed %d: no boss room placed", seed)
+		}
+		if worldMap[shop.Y][shop.X].Type != Shop {
+			t.Fatalf("seed %d: the shop became room type %v", seed, worldMap[shop.Y][shop.X].Type)
+		}
+		if !isAdjacentTo(stairs, worldMap, Boss) {
+			t.Fatalf("seed %d: the boss room is not next to the stairs", seed)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package game
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,8 +27,8 @@ func (m model) updateCombat(msg tea.Msg) (tea.Model, tea.Cmd) {
 			enemy := m.combat.turnOrder[m.combat.turnIndex].(*Foe)
 			player := m.combat.player
 
-			if len(enemy.Attacks) > 0 {
-				selectedAttack := enemy.Attacks[rand.Intn(len(enemy.Attacks))]
+			if attacks := enemy.currentAttacks(); len(attacks) > 0 {
+				selectedAttack := attacks[rand.Intn(len(attacks))]
 
 				roll := rand.Intn(selectedAttack.Sides) + 1
 				damage := roll + enemy.Strength - (player.data.stats.defense / 2)
@@ -68,6 +69,9 @@ func (m model) updateCombat(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case tea.KeyMsg:
+		if m.combat.showIntro {
+			return m.updateBossIntro(msg)
+		}
 		if m.combat.turnOrder[m.combat.turnIndex].IsPlayer() {
 			switch m.combat.actionState {
 			case ActionSelect:
@@ -83,6 +87,10 @@ func (m model) updateCombat(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) renderCombatView() string {
+	if m.combat.showIntro {
+		return m.renderBossIntro()
+	}
+
 	turnOrderContent := m.renderTurnOrder()
 	playerStatsContent := m.renderPlayerStatsCombat()
 	enemiesContent := m.renderEnemies()
@@ -108,6 +116,9 @@ func (m model) renderCombatView() string {
 	if m.combat.isEnemyTurnInProgress {
 		enemyName := m.combat.turnOrder[m.combat.turnIndex].GetName()
 		actionText := fmt.Sprintf("%s is attacking!", enemyName)
+		if m.combat.surprise {
+			actionText = "Ambush! " + actionText
+		}
 		progressBar := m.combat.enemyActionProgress.View()
 		middleSection = lipgloss.JoinVertical(lipgloss.Center, actionText, progressBar)
 	} else {
@@ -225,10 +236,7 @@ func (m model) handleSubActionSelect(msg tea.KeyMsg) (model, tea.Cmd) {
 		}
 
 		if m.combat.actionState == ItemSelect {
-			var itemIDs []string
-			for id := range m.combat.player.data.inventory {
-				itemIDs = append(itemIDs, id)
-			}
+			itemIDs := combatItemIDs(m.combat.player.data.inventory)
 
 			if len(itemIDs) == 0 {
 				return m, nil
@@ -271,9 +279,7 @@ func (m model) handleTargetSelect(msg tea.KeyMsg) (model, tea.Cmd) {
 	}
 
 	if len(aliveEnemies) == 0 {
-		m.state = StateGame
-		m.combat = nil
-		return m, nil
+		return m.endCombat(), nil
 	}
 
 	switch msg.String() {
@@ -339,9 +345,7 @@ func (m model) handleTargetSelect(msg tea.KeyMsg) (model, tea.Cmd) {
 				}
 			}
 			if !hasAliveEnemies {
-				m.state = StateGame
-				m.combat = nil
-				return m, nil
+				return m.endCombat(), nil
 			}
 		}
 		m = m.advanceTurn()
@@ -408,6 +412,9 @@ func (m model) renderEnemies() string {
 	for i, enemy := range aliveEnemies {
 		hp := fmt.Sprintf("HP: %d/%d", enemy.GetHP(), enemy.GetMaxHP())
 		name := enemy.GetName()
+		if phase := enemy.currentPhase(); phase >= 0 {
+			name += fmt.Sprintf(" (%s)", enemy.Phases[phase].Name)
+		}
 
 		view := lipgloss.JoinVertical(lipgloss.Center, hp, name)
 		enemyStyle := lipgloss.NewStyle().Margin(0, 2)
@@ -468,10 +475,7 @@ func (m model) renderActionMenu() string {
 		content = "Magics: " + strings.Join(magicOptions, " | ")
 	case ItemSelect:
 		var itemOptions []string
-		var itemIDs []string
-		for id := range m.combat.player.data.inventory {
-			itemIDs = append(itemIDs, id)
-		}
+		itemIDs := combatItemIDs(m.combat.player.data.inventory)
 		if len(itemIDs) == 0 {
 			content = "Items: (Empty)"
 		} else {
@@ -492,6 +496,90 @@ func (m model) renderActionMenu() string {
 	return lipgloss.NewStyle().Align(lipgloss.Center).Render(content)
 }
 
+// combatItemIDs lists the inventory items that can be used in combat, in a
+// stable order so the menu and the selection always agree.
+func combatItemIDs(inventory map[string]int) []string {
+	var itemIDs []string
+	for id := range inventory {
+		if ItemTemplates[id].Effect == "heal" {
+			itemIDs = append(itemIDs, id)
+		}
+	}
+	sort.Strings(itemIDs)
+	return itemIDs
+}
+
+// startCombat switches to the combat screen against the given enemies. In a
+// surprise attack the enemies act before the player.
+func (m model) startCombat(enemies []*Foe, surprise bool) model {
+	m.state = StateCombat
+
+	var playerAttacks []Attack
+	for _, attacks := range AttackTemplates {
+		playerAttacks = append(playerAttacks, attacks)
+	}
+	var playerMagics []Magic
+	for _, magics := range MagicTemplates {
+		playerMagics = append(playerMagics, magics)
+	}
+
+	if m.player.inventory == nil {
+		m.player.inventory = make(map[string]int)
+	}
+
+	playerEntity := &Player{
+		data:    &m.player,
+		Attacks: playerAttacks,
+		Magics:  playerMagics,
+	}
+
+	turnOrder := calculateTurnOrder(playerEntity, enemies, surprise)
+
+	enemyProgressBar := progress.New(
+		progress.WithGradient(string(indigo), string(orange)),
+		progress.WithoutPercentage(),
+	)
+
+	m.combat = &CombatState{
+		player:                playerEntity,
+		enemies:               enemies,
+		turnOrder:             turnOrder,
+		turnIndex:             0,
+		actionState:           ActionSelect,
+		isEnemyTurnInProgress: false,
+		enemyActionProgress:   enemyProgressBar,
+		surprise:              surprise,
+	}
+	return m
+}
+
+// endCombat returns to the map once every enemy is down. Beating a boss
+// unseals the stairs of its floor, and beating the last enemy completes a
+// slay objective.
+func (m model) endCombat() model {
+	if m.combat.boss != "" && len(m.floors) > 0 {
+		f := &m.floors[m.currentFloor]
+		f.bossDefeated = true
+		f.worldMap[m.playerMapY][m.playerMapX].Type = Empty
+		m.message = fmt.Sprintf("%s has fallen! The stairs are open.", BossTemplates[m.combat.boss].Name)
+	}
+	if len(m.floors) > 0 {
+		m.floors[m.currentFloor].updateObjective()
+	}
+	m.state = StateGame
+	m.combat = nil
+	return m
+}
+
+// openCombat starts the first enemy turn when an enemy is faster than the
+// player. Bosses wait until their intro is dismissed.
+func (m model) openCombat() (model, tea.Cmd) {
+	if m.combat.showIntro || m.combat.turnOrder[m.combat.turnIndex].IsPlayer() {
+		return m, nil
+	}
+	return m.startEnemyTurn()
+}
+
 func (m model) advanceTurn() model {
 	var aliveInTurnOrder []CombatEntity
 	for _, entity := range m.combat.turnOrder {
@@ -507,6 +595,9 @@ func (m model) advanceTurn() model {
 
 	m.combat.actionState = ActionSelect
 	m.combat.player.isDefending = false
+	if m.combat.turnOrder[m.combat.turnIndex].IsPlayer() {
+		m.combat.surprise = false
+	}
 	return m
 }
 
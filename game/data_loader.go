@@ -10,6 +10,20 @@ var (
 	AttackTemplates map[string]Attack
 	MagicTemplates  map[string]Magic
 	ItemTemplates   map[string]Item
+	LootTemplates   map[string]LootTable
+	TrapTemplates   map[string]TrapTemplate
+	BossTemplates   map[string]BossTemplate
+	ThemeTemplates  map[string]Theme
+
+	RespawnTemplates map[string]RespawnRule
+
+	ObjectiveTemplates []ObjectiveTable
+
+	LayoutTemplates map[int]*Layout
+
+	DescriptionTemplates DescriptionData
+
+	FloorProgression []FloorParams
 )
 
 func LoadGameData() error {
@@ -25,6 +39,50 @@ func LoadGameData() error {
 	if err := loadFile("data/items.json", &ItemTemplates); err != nil {
 		return err
 	}
+	if err := loadFile("data/traps.json", &TrapTemplates); err != nil {
+		return err
+	}
+	if err := loadFile("data/bosses.json", &BossTemplates); err != nil {
+		return err
+	}
+	if err := loadFile("data/loot.json", &LootTemplates); err != nil {
+		return err
+	}
+	if err := loadFile("data/respawn.json", &RespawnTemplates); err != nil {
+		return err
+	}
+	if err := validateRespawnRules(RespawnTemplates); err != nil {
+		return err
+	}
+	if err := loadFile("data/floors.json", &FloorProgression); err != nil {
+		return err
+	}
+	if err := validateProgression(FloorProgression); err != nil {
+		return err
+	}
+	if err := loadFile("data/objectives.json", &ObjectiveTemplates); err != nil {
+		return err
+	}
+	if err := validateObjectives(ObjectiveTemplates); err != nil {
+		return err
+	}
+	if err := loadFile("data/themes.json", &ThemeTemplates); err != nil {
+		return err
+	}
+	if err := validateThemes(ThemeTemplates); err != nil {
+		return err
+	}
+	if err := loadFile("data/descriptions.json", &DescriptionTemplates); err != nil {
+		return err
+	}
+	if err := parseDescriptions(); err != nil {
+		return err
+	}
+	layouts, err := loadLayouts(layoutsDir)
+	if err != nil {
+		return err
+	}
+	LayoutTemplates = layouts
 
 	return nil
 }
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+)
+
+// DescriptionData is data/descriptions.json. Rooms holds text/template
+// sources for each kind of room (see roomTypeKey). Templates can use the
+// fields of roomTemplateData and these functions:
+//
+//	pick "name"  a random line from Fragments (the floor theme's first)
+//	lower s      s in lower case
+//	exits n      a sentence about the n passages of the room
+//
+// Nearby holds one sentence per kind of neighboring room; {dir} is replaced
+// by the direction of that room.
+type DescriptionData struct {
+	Rooms     map[string][]string `json:"rooms"`
+	Fragments map[string][]string `json:"fragments"`
+	Nearby    map[string]string   `json:"nearby"`
+}
+
+type roomTemplateData struct {
+	Theme  string
+	Depth  int
+	Exits  int
+	Nearby []string
+}
+
+// roomTemplates holds the parsed templates, keyed by room kind for the
+// defaults and by "theme/kind" for theme descriptions.
+var roomTemplates map[string][]*template.Template
+
+var directionNames = [4]string{"north", "south", "west", "east"}
+
+func templateFuncs(pick func(string) string) template.FuncMap {
+	return template.FuncMap{
+		"pick":  pick,
+		"lower": strings.ToLower,
+		"exits": func(n int) string {
+			if n == 1 {
+				return "A single passage leads out."
+			}
+			return fmt.Sprintf("Passages lead in %d directions.", n)
+		},
+	}
+}
+
+// parseDescriptions compiles the default and theme descriptions so broken
+// templates are reported when the game data is loaded.
+func parseDescriptions() error {
+	roomTemplates = make(map[string][]*template.Template)
+	parse := func(key string, sources []string) error {
+		for i, src := range sources {
+			noPick := func(string) string { return "" }
+			tmpl, err := template.New(key).Funcs(templateFuncs(noPick)).Parse(src)
+			if err != nil {
+				return fmt.Errorf("descriptions: %s #%d: %w", key, i, err)
+			}
+			roomTemplates[key] = append(roomTemplates[key], tmpl)
+		}
+		return nil
+	}
+
+	for kind, sources := range DescriptionTemplates.Rooms {
+		if err := parse(kind, sources); err != nil {
+			return err
+		}
+	}
+	for id, theme := range ThemeTemplates {
+		for kind, sources := range theme.Descriptions {
+			if err := parse(id+"/"+kind, sources); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describeRoom returns the description of the room at x, y on the current
+// floor. The floor theme's templates are used first, then the default ones.
+// The text only depends on the floor seed and the room, so it does not change
+// while the player stands there. Detected traps always show the trap itself.
+func (m model) describeRoom(x, y int) string {
+	f := m.floors[m.currentFloor]
+	r := f.worldMap[y][x]
+	if r.Type == Trap && r.TrapDetected {
+		return r.getRoomDescription()
+	}
+
+	kind := roomTypeKey(r.Type)
+	templates := roomTemplates[f.theme+"/"+kind]
+	if len(templates) == 0 {
+		templates = roomTemplates[kind]
+	}
+	if len(templates) == 0 {
+		return r.getRoomDescription()
+	}
+
+	theme, hasTheme := ThemeTemplates[f.theme]
+	data := roomTemplateData{Theme: "Dungeon", Depth: m.currentFloor}
+	if hasTheme {
+		data.Theme = theme.Name
+	}
+	for dir, exit := range r.Exits {
+		if exit == nil {
+			continue
+		}
+		neighbor := f.worldMap[y+cardinalDirections[dir].Y][x+cardinalDirections[dir].X]
+		if neighbor.Hidden {
+			continue
+		}
+		data.Exits++
+		if phrase, ok := DescriptionTemplates.Nearby[roomTypeKey(neighbor.Type)]; ok && neighbor.Seen {
+			data.Nearby = append(data.Nearby, strings.ReplaceAll(phrase, "{dir}", directionNames[dir]))
+		}
+	}
+
+	rng := rand.New(rand.NewSource(roomSeed(f.seed, x, y)))
+	pick := func(name string) string {
+		lines := theme.Fragments[name]
+		if len(lines) == 0 {
+			lines = DescriptionTemplates.Fragments[name]
+		}
+		if len(lines) == 0 {
+			return ""
+		}
+		return lines[rng.Intn(len(lines))]
+	}
+
+	tmpl, err := templates[rng.Intn(len(templates))].Clone()
+	if err != nil {
+		return r.getRoomDescription()
+	}
+	var out bytes.Buffer
+	if err := tmpl.Funcs(templateFuncs(pick)).Execute(&out, data); err != nil {
+		return r.getRoomDescription()
+	}
+	return out.String()
+}
@@ -0,0 +1,534 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The editor grid is at least this big, so new layouts have room to grow.
+const (
+	editorWidth      = 12
+	editorHeight     = 8
+	editorPanelWidth = 40
+	maxArgsLength    = 40
+)
+
+// layoutNames keeps layout files inside layoutsDir.
+var layoutNames = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// editorBrush is something the editor can paint on a room: a plain map
+// symbol, or a legend kind with its default arguments.
+type editorBrush struct {
+	name   string
+	symbol rune
+	kind   string
+	args   []string
+}
+
+var editorBrushes = []editorBrush{
+	{name: "Empty Room", symbol: '.'},
+	{name: "Start (floor 0)", symbol: '@'},
+	{name: "Monster Lair", symbol: 'E'},
+	{name: "Treasure Room", symbol: 'T'},
+	{name: "Shop", symbol: '$'},
+	{name: "Trapped Room", symbol: '^'},
+	{name: "Boss Chamber", symbol: 'B'},
+	{name: "Stairs Up", symbol: '▲'},
+	{name: "Stairs Down", symbol: '▼'},
+	{name: "Teleporter", kind: "teleporter"},
+	{name: "Pit", kind: "pit", args: []string{"1"}},
+	{name: "Key", kind: "key"},
+	{name: "Rune", kind: "rune"},
+	{name: "Lever", kind: "switch"},
+	{name: "Secret Room", kind: "secret", args: []string{"secret"}},
+}
+
+// plainKinds are the legend kinds of the plain symbols that can also take
+// arguments: 'E' with a fixed group is an "enemy" entry, and so on.
+var plainKinds = map[rune]string{
+	'E': "enemy",
+	'T': "treasure",
+	'$': "shop",
+	'^': "trap",
+	'B': "boss",
+}
+
+// legendSymbols are handed out to new legend entries in this order.
+const legendSymbols = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// editorState is the level editor opened with `ssh host edit <name>`. It
+// works on the layout text directly, so saving it is just formatting it.
+type editorState struct {
+	layout *Layout
+	cursor image.Point
+	brush  int
+
+	// While editingArgs is set, keys type argsDraft: the enemy group, loot
+	// table or drop of the room under the cursor.
+	editingArgs bool
+	argsDraft   string
+
+	dirty    bool
+	quitting bool
+	message  string
+	// problem is why the layout would not load, nil when it is valid.
+	problem error
+}
+
+// newEditorState opens the layout called name, or starts a new one if there
+// is no such file.
+func newEditorState(name string) *editorState {
+	e := &editorState{}
+	if !layoutNames.MatchString(name) {
+		name = "untitled"
+		e.message = "Layout names use a-z, 0-9, '-' and '_'. "
+	}
+	e.layout = &Layout{Name: name, Legend: map[rune]LegendEntry{}}
+
+	path := filepath.Join(layoutsDir, name+".txt")
+	if file, err := os.Open(path); err != nil {
+		e.message += "New layout " + path + "."
+	} else {
+		l, err := parseLayout(path, bufio.NewScanner(file))
+		file.Close()
+		if err != nil {
+			e.message += "Could not read the layout: " + err.Error()
+		} else {
+			l.Name = name
+			e.layout = l
+			e.message += "Editing " + path + "."
+		}
+	}
+	e.problem = e.layout.check()
+	return e
+}
+
+func (e *editorState) size() (int, int) {
+	width, height := e.layout.size()
+	return max(width, editorWidth), max(height, editorHeight)
+}
+
+// cell is the symbol of the room at x, y, or ' ' if there is none.
+func (e *editorState) cell(x, y int) rune {
+	return e.layout.at(2*x, 2*y)
+}
+
+// set writes symbol at x, y of the layout text, growing it as needed.
+func (l *Layout) set(x, y int, symbol rune) {
+	for len(l.Map) <= y {
+		l.Map = append(l.Map, nil)
+	}
+	for len(l.Map[y]) <= x {
+		l.Map[y] = append(l.Map[y], ' ')
+	}
+	l.Map[y][x] = symbol
+}
+
+// entryOf is the legend entry of a symbol, with the plain symbols that take
+// arguments as entries without them.
+func (l *Layout) entryOf(symbol rune) (LegendEntry, bool) {
+	if kind, ok := plainKinds[symbol]; ok {
+		return LegendEntry{Kind: kind}, true
+	}
+	entry, ok := l.Legend[symbol]
+	return entry, ok
+}
+
+// plainSymbol is the plain symbol of a legend kind, if it has one.
+func plainSymbol(kind string) (rune, bool) {
+	for symbol, plainKind := range plainKinds {
+		if plainKind == kind {
+			return symbol, true
+		}
+	}
+	return 0, false
+}
+
+// symbolFor returns the symbol of entry, adding it to the legend if no
+// symbol has it yet. Entries without arguments of a plain kind use the
+// plain symbol.
+func (l *Layout) symbolFor(entry LegendEntry) rune {
+	if symbol, ok := plainSymbol(entry.Kind); ok && len(entry.Args) == 0 {
+		return symbol
+	}
+
+	used := map[rune]int{}
+	for _, row := range l.Map {
+		for _, symbol := range row {
+			used[symbol]++
+		}
+	}
+	for symbol, other := range l.Legend {
+		if used[symbol] > 0 && other.Kind == entry.Kind && slices.Equal(other.Args, entry.Args) {
+			// Teleporters pair up by symbol: only a lone one can be reused.
+			if entry.Kind != "teleporter" || used[symbol] == 1 {
+				return symbol
+			}
+		}
+	}
+	for _, symbol := range legendSymbols {
+		if used[symbol] == 0 {
+			l.Legend[symbol] = entry
+			return symbol
+		}
+	}
+	return '?'
+}
+
+// paint puts the current brush on the room under the cursor.
+func (e *editorState) paint() {
+	brush := editorBrushes[e.brush]
+	symbol := brush.symbol
+	if symbol == 0 {
+		// Clear the room first, so a teleporter does not pair with itself.
+		e.layout.set(2*e.cursor.X, 2*e.cursor.Y, ' ')
+		symbol = e.layout.symbolFor(LegendEntry{Kind: brush.kind, Args: brush.args})
+	}
+	e.layout.set(2*e.cursor.X, 2*e.cursor.Y, symbol)
+	e.changed("Painted " + brush.name + ".")
+}
+
+// erase removes the room under the cursor and its passages.
+func (e *editorState) erase() {
+	if e.cell(e.cursor.X, e.cursor.Y) == ' ' {
+		return
+	}
+	x, y := 2*e.cursor.X, 2*e.cursor.Y
+	e.layout.set(x, y, ' ')
+	for _, offset := range cardinalDirections {
+		if e.layout.at(x+offset.X, y+offset.Y) != ' ' {
+			e.layout.set(x+offset.X, y+offset.Y, ' ')
+		}
+	}
+	e.changed("Room removed.")
+}
+
+// togglePassage cycles the passage towards dir through open, locked and
+// none.
+func (e *editorState) togglePassage(dir direction) {
+	next := e.cursor.Add(cardinalDirections[dir])
+	if e.cell(e.cursor.X, e.cursor.Y) == ' ' || e.cell(next.X, next.Y) == ' ' {
+		e.message = "A passage needs a room on both sides."
+		return
+	}
+	x, y := 2*e.cursor.X+cardinalDirections[dir].X, 2*e.cursor.Y+cardinalDirections[dir].Y
+	open := '-'
+	if dir == North || dir == South {
+		open = '|'
+	}
+	switch e.layout.at(x, y) {
+	case ' ':
+		e.layout.set(x, y, open)
+		e.changed("Passage opened.")
+	case '#':
+		e.layout.set(x, y, ' ')
+		e.changed("Passage removed.")
+	default:
+		e.layout.set(x, y, '#')
+		e.changed("Passage locked.")
+	}
+}
+
+// setArgs gives the room under the cursor the arguments typed in the draft.
+// Plain symbols go back to themselves when the arguments are cleared.
+func (e *editorState) setArgs() {
+	entry, _ := e.layout.entryOf(e.cell(e.cursor.X, e.cursor.Y))
+	entry.Args = strings.Fields(e.argsDraft)
+	if _, plain := plainSymbol(entry.Kind); !argsFit(entry.Kind, len(entry.Args)) && !(plain && len(entry.Args) == 0) {
+		e.message = fmt.Sprintf("Wrong number of arguments for %s.", entry.Kind)
+		return
+	}
+	e.layout.set(2*e.cursor.X, 2*e.cursor.Y, ' ')
+	e.layout.set(2*e.cursor.X, 2*e.cursor.Y, e.layout.symbolFor(entry))
+	e.changed("Room updated.")
+}
+
+func (e *editorState) changed(message string) {
+	e.dirty, e.quitting = true, false
+	e.message = message
+	e.problem = e.layout.check()
+}
+
+func (e *editorState) save() {
+	if e.problem != nil {
+		e.message = "Fix the layout before saving: " + e.problem.Error()
+		return
+	}
+	if err := saveLayout(layoutsDir, e.layout); err != nil {
+		e.message = "Could not save: " + err.Error()
+		return
+	}
+	e.dirty = false
+	e.message = fmt.Sprintf("Saved %s. It is used from the next server start.", filepath.Join(layoutsDir, e.layout.Name+".txt"))
+}
+
+// themeIDs lists the themes in the order the theme key cycles through
+// them, starting with no fixed theme.
+func themeIDs() []string {
+	ids := []string{""}
+	for id := range ThemeTemplates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids[1:])
+	return ids
+}
+
+func (m model) updateEditor(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	e := m.editor
+	if e.editingArgs {
+		e.updateArgs(keyMsg)
+		return m, nil
+	}
+
+	width, height := e.size()
+	switch keyMsg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q":
+		if e.dirty && !e.quitting {
+			e.quitting = true
+			e.message = "There are unsaved changes. Press esc again to quit without saving."
+			return m, nil
+		}
+		return m, tea.Quit
+	case "up":
+		e.cursor.Y = max(0, e.cursor.Y-1)
+	case "down":
+		e.cursor.Y = min(height-1, e.cursor.Y+1)
+	case "left":
+		e.cursor.X = max(0, e.cursor.X-1)
+	case "right":
+		e.cursor.X = min(width-1, e.cursor.X+1)
+	case "shift+up":
+		e.togglePassage(North)
+	case "shift+down":
+		e.togglePassage(South)
+	case "shift+left":
+		e.togglePassage(West)
+	case "shift+right":
+		e.togglePassage(East)
+	case "tab":
+		e.brush = (e.brush + 1) % len(editorBrushes)
+	case "shift+tab":
+		e.brush = (e.brush + len(editorBrushes) - 1) % len(editorBrushes)
+	case "enter", " ":
+		e.paint()
+	case "backspace", "delete":
+		e.erase()
+	case "e":
+		entry, ok := e.layout.entryOf(e.cell(e.cursor.X, e.cursor.Y))
+		if !ok || legendKinds[entry.Kind] == 0 {
+			e.message = "This room has nothing to set up."
+			return m, nil
+		}
+		e.editingArgs = true
+		e.argsDraft = strings.Join(entry.Args, " ")
+	case "+":
+		e.layout.Depth++
+		e.changed(fmt.Sprintf("Depth %d.", e.layout.Depth))
+	case "-":
+		if e.layout.Depth > 0 {
+			e.layout.Depth--
+			e.changed(fmt.Sprintf("Depth %d.", e.layout.Depth))
+		}
+	case "t":
+		ids := themeIDs()
+		e.layout.Theme = ids[(slices.Index(ids, e.layout.Theme)+1)%len(ids)]
+		e.changed("Theme changed.")
+	case "]":
+		e.layout.Wanderers++
+		e.changed("Wandering groups changed.")
+	case "[":
+		if e.layout.Wanderers > 0 {
+			e.layout.Wanderers--
+			e.changed("Wandering groups changed.")
+		}
+	case "s":
+		e.save()
+	default:
+		// Typing a plain symbol picks its brush.
+		for i, brush := range editorBrushes {
+			if string(brush.symbol) == keyMsg.String() {
+				e.brush = i
+			}
+		}
+	}
+	return m, nil
+}
+
+// updateArgs types the arguments of the room under the cursor, like notes on
+// the full map.
+func (e *editorState) updateArgs(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		e.editingArgs = false
+		e.setArgs()
+	case tea.KeyEsc:
+		e.editingArgs = false
+	case tea.KeyBackspace:
+		if runes := []rune(e.argsDraft); len(runes) > 0 {
+			e.argsDraft = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		if len([]rune(e.argsDraft))+len(msg.Runes) <= maxArgsLength {
+			e.argsDraft += string(msg.Runes)
+		}
+	}
+}
+
+func (m model) renderEditorView() string {
+	e := m.editor
+	title := m.styles.Title.Render("Level Editor: " + e.layout.Name)
+	grid := m.styles.MapBorder.Render(m.renderEditorGrid())
+
+	side := m.styles.Panel.Width(editorPanelWidth).Render(strings.Join([]string{
+		m.renderEditorBrushes(),
+		"",
+		m.renderEditorRoom(),
+		"",
+		m.renderEditorFloor(),
+	}, "\n"))
+
+	status := m.styles.Help.Render("✓ The layout is valid.")
+	if e.problem != nil {
+		status = m.styles.Locked.Render("✗ " + e.problem.Error())
+	}
+	help := m.styles.Faint.Render("arrows: move | shift+arrows: passage | tab or a symbol: brush | enter/space: paint | del: erase | 'e': set up room\n" +
+		"'+'/'-': depth | 't': theme | '['/']': wanderers | 's': save | esc: quit")
+
+	body := lipgloss.JoinHorizontal(lipgloss.Top, grid, side)
+	view := lipgloss.JoinVertical(lipgloss.Left, title, body, status, e.message, help)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, view)
+}
+
+// renderEditorGrid draws the layout like the floor map, with the empty spots
+// of the grid as faint dots.
+func (m model) renderEditorGrid() string {
+	e := m.editor
+	width, height := e.size()
+	var rows []string
+	for y := range height {
+		var roomRow, linkRow strings.Builder
+		for x := range width {
+			symbol := e.cell(x, y)
+			cell, style := " · ", m.styles.Room.Inherit(m.styles.Faint)
+			if symbol != ' ' {
+				cell, style = fmt.Sprintf("[%c]", symbol), m.styles.Room.Inherit(m.editorSymbolStyle(symbol))
+			}
+			if x == e.cursor.X && y == e.cursor.Y {
+				style = style.Reverse(true)
+			}
+			roomRow.WriteString(style.Render(cell))
+
+			if x < width-1 {
+				roomRow.WriteString(m.renderEditorPassage(e.layout.at(2*x+1, 2*y), "─"))
+			}
+			linkRow.WriteString(" " + m.renderEditorPassage(e.layout.at(2*x, 2*y+1), "│") + " ")
+			if x < width-1 {
+				linkRow.WriteString(" ")
+			}
+		}
+		rows = append(rows, roomRow.String())
+		if y < height-1 {
+			rows = append(rows, linkRow.String())
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (m model) renderEditorPassage(symbol rune, open string) string {
+	switch symbol {
+	case ' ':
+		return " "
+	case '#':
+		return m.styles.Locked.Render("■")
+	}
+	return open
+}
+
+// editorSymbolStyle colors rooms like the floor map does.
+func (m model) editorSymbolStyle(symbol rune) lipgloss.Style {
+	entry, _ := m.editor.layout.entryOf(symbol)
+	switch {
+	case symbol == 'B' || entry.Kind == "boss":
+		return m.styles.RoomBoss
+	case entry.Kind == "secret":
+		return m.styles.RoomSecret
+	case entry.Kind == "enemy" || entry.Kind == "trap":
+		return m.styles.Locked
+	case symbol == '.' || symbol == '@' || symbol == '▼':
+		return m.styles.Room
+	}
+	return m.styles.RoomSpecial
+}
+
+func (m model) renderEditorBrushes() string {
+	lines := []string{m.styles.Title.Render("Brush")}
+	for i, brush := range editorBrushes {
+		// Legend kinds get their symbol when they are painted.
+		symbol := "·"
+		if brush.symbol != 0 {
+			symbol = string(brush.symbol)
+		}
+		line := fmt.Sprintf("  [%s] %s", symbol, brush.name)
+		if i == m.editor.brush {
+			line = m.styles.Selected.Render("> " + line[2:])
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderEditorRoom describes the room under the cursor as the legend does.
+func (m model) renderEditorRoom() string {
+	e := m.editor
+	symbol := e.cell(e.cursor.X, e.cursor.Y)
+	lines := []string{m.styles.Title.Render(fmt.Sprintf("Room %d,%d", e.cursor.X, e.cursor.Y))}
+	switch {
+	case symbol == ' ':
+		lines = append(lines, "No room.")
+	default:
+		description := fmt.Sprintf("[%c]", symbol)
+		for _, brush := range editorBrushes {
+			if brush.symbol == symbol {
+				description += " " + brush.name
+			}
+		}
+		if entry, ok := e.layout.Legend[symbol]; ok {
+			description += " " + strings.TrimSpace(entry.Kind+" "+strings.Join(entry.Args, " "))
+		}
+		lines = append(lines, description)
+	}
+	if e.editingArgs {
+		lines = append(lines, "Set up: "+e.argsDraft+"_")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m model) renderEditorFloor() string {
+	l := m.editor.layout
+	theme := "by depth"
+	if l.Theme != "" {
+		theme = l.Theme
+	}
+	return strings.Join([]string{
+		m.styles.Title.Render("Floor"),
+		fmt.Sprintf("Depth: %d", l.Depth),
+		fmt.Sprintf("Theme: %s", theme),
+		fmt.Sprintf("Wandering groups: %d", l.Wanderers),
+	}, "\n")
+}
@@ -51,6 +51,7 @@ type Item struct {
 	Name   string
 	Effect string
 	Value  int
+	Price  int
 }
 
 type Effect struct {
@@ -80,6 +81,11 @@ func (p *Player) ModifyStat(stat string, value int) {
 		p.data.stats.speed += value
 	case "Magic":
 		p.data.stats.magic += value
+	case "Mana":
+		p.data.stats.mana += value
+		if p.data.stats.mana < 0 {
+			p.data.stats.mana = 0
+		}
 	}
 }
 
@@ -91,6 +97,8 @@ type Foe struct {
 	Defense  int      `json:"defense"`
 	Strength int      `json:"strength"`
 	Attacks  []Attack `json:"attacks"`
+
+	Phases []BossPhase `json:"phases,omitempty"`
 }
 
 func (e *Foe) GetName() string       { return e.Name }
@@ -116,13 +124,21 @@ func (e *Foe) ModifyStat(stat string, value int) {
 }
 
 func newGoblin() *Foe {
-	goblin := EnemyTemplates["goblin"]
-	return &goblin
+	return newFoe("goblin")
+}
+
+func newFoe(id string) *Foe {
+	foe := EnemyTemplates[id]
+	return &foe
 }
 
-func calculateTurnOrder(player *Player, enemies []*Foe) []CombatEntity {
+// calculateTurnOrder sorts everyone by speed. When the player is surprised
+// all the enemies act first and the player goes last.
+func calculateTurnOrder(player *Player, enemies []*Foe, surprise bool) []CombatEntity {
 	entities := make([]CombatEntity, 0, len(enemies)+1)
-	entities = append(entities, player)
+	if !surprise {
+		entities = append(entities, player)
+	}
 	for _, e := range enemies {
 		entities = append(entities, e)
 	}
@@ -131,6 +147,9 @@ func calculateTurnOrder(player *Player, enemies []*Foe) []CombatEntity {
 		return entities[i].GetSpeed() > entities[j].GetSpeed()
 	})
 
+	if surprise {
+		entities = append(entities, player)
+	}
 	return entities
 }
 
@@ -171,7 +190,7 @@ func newTestCombatState() *CombatState {
 		enemies[i] = newGoblin()
 	}
 
-	turnOrder := calculateTurnOrder(playerEntity, enemies)
+	turnOrder := calculateTurnOrder(playerEntity, enemies, false)
 
 	enemyProgressBar := progress.New(
 		progress.WithGradient(string(indigo), string(orange)),
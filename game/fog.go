@@ -0,0 +1,48 @@
+package game
+
+// revealAround marks the rooms connected to the player's room as seen, so
+// they show up on the map before being visited.
+func (m model) revealAround() {
+	currentMap := m.floors[m.currentFloor].worldMap
+	current := currentMap[m.playerMapY][m.playerMapX]
+	current.Seen = true
+
+	for dir, exit := range current.Exits {
+		if exit == nil {
+			continue
+		}
+		neighbor := currentMap[m.playerMapY+cardinalDirections[dir].Y][m.playerMapX+cardinalDirections[dir].X]
+		if !neighbor.Hidden {
+			neighbor.Seen = true
+		}
+	}
+}
+
+// revealFloor marks every room and passage of the current floor as seen.
+// Secret rooms still need to be found by searching.
+func (m model) revealFloor() {
+	m.floors[m.currentFloor].mapped = true
+	for _, row := range m.floors[m.currentFloor].worldMap {
+		for _, r := range row {
+			if r != nil && !r.Hidden {
+				r.Seen = true
+			}
+		}
+	}
+}
+
+// readMapScroll uses a map scroll from the inventory to reveal the floor.
+func (m model) readMapScroll() model {
+	if m.player.inventory["map_scroll"] <= 0 {
+		m.message = "You have no map scroll."
+		return m
+	}
+	m.player.inventory["map_scroll"]--
+	if m.player.inventory["map_scroll"] == 0 {
+		delete(m.player.inventory, "map_scroll")
+	}
+
+	m.revealFloor()
+	m.message = "You read the map scroll. The layout of the floor appears before you."
+	return m
+}
@@ -0,0 +1,190 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	fullMapPanelWidth = 34
+	maxNoteLength     = 40
+)
+
+// roomMarkers are the symbols the player can put on rooms, in the order the
+// marker key cycles through them. The empty marker removes it.
+var roomMarkers = []string{"", "!", "*", "x", "~"}
+
+func (m model) updateFullMap(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editingNote {
+		return m.updateNote(msg), nil
+	}
+
+	worldMap := m.floors[m.currentFloor].worldMap
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "m", "esc", "q":
+		m.showFullMap = false
+	case "up", "w":
+		m.mapCursor.Y = max(0, m.mapCursor.Y-1)
+	case "down", "s":
+		m.mapCursor.Y = min(len(worldMap)-1, m.mapCursor.Y+1)
+	case "left", "a":
+		m.mapCursor.X = max(0, m.mapCursor.X-1)
+	case "right", "d":
+		m.mapCursor.X = min(len(worldMap[0])-1, m.mapCursor.X+1)
+	case "n":
+		if r := m.cursorRoom(); r != nil {
+			m.editingNote = true
+			m.noteDraft = r.Note
+		}
+	case "x":
+		if r := m.cursorRoom(); r != nil {
+			next := (slices.Index(roomMarkers, r.Marker) + 1) % len(roomMarkers)
+			r.Marker = roomMarkers[next]
+		}
+	case "t", "enter":
+		m.showFullMap = false
+		return m.travelTo(m.mapCursor)
+	}
+	return m, nil
+}
+
+// clickFullMap moves the cursor to the clicked room. Clicking the room under
+// the cursor travels there.
+func (m model) clickFullMap(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.editingNote || !isLeftClick(msg) {
+		return m, nil
+	}
+	p, ok := m.fullMapClickedRoom(msg)
+	if !ok {
+		return m, nil
+	}
+	if p == m.mapCursor {
+		m.showFullMap = false
+		return m.travelTo(p)
+	}
+	m.mapCursor = p
+	return m, nil
+}
+
+// updateNote types the note of the room under the cursor. Enter saves it, an
+// empty note removes it and esc leaves the room as it was.
+func (m model) updateNote(msg tea.KeyMsg) model {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.cursorRoom().Note = strings.TrimSpace(m.noteDraft)
+		m.editingNote = false
+	case tea.KeyEsc:
+		m.editingNote = false
+	case tea.KeyBackspace:
+		if runes := []rune(m.noteDraft); len(runes) > 0 {
+			m.noteDraft = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		if len([]rune(m.noteDraft))+len(msg.Runes) <= maxNoteLength {
+			m.noteDraft += string(msg.Runes)
+		}
+	}
+	return m
+}
+
+// cursorRoom is the room under the full map cursor, or nil if the player
+// knows nothing about that spot.
+func (m model) cursorRoom() *room {
+	r := m.floors[m.currentFloor].worldMap[m.mapCursor.Y][m.mapCursor.X]
+	if r == nil || r.Hidden || !r.Seen {
+		return nil
+	}
+	return r
+}
+
+// renderFullMap shows the floor map over the whole screen, with the legend
+// and the room under the cursor next to it.
+func (m model) renderFullMap() string {
+	title, mapView, sideView, help := m.fullMapParts()
+	body := lipgloss.JoinHorizontal(lipgloss.Top, mapView, sideView)
+	content := lipgloss.JoinVertical(lipgloss.Center, title, body, help)
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, content)
+}
+
+func (m model) fullMapParts() (title, mapView, sideView, help string) {
+	title = m.styles.Title.Render(fmt.Sprintf("Floor %d", m.currentFloor))
+	helpText := "arrows/wasd/click: move cursor | 't'/enter: travel | 'n': note | 'x': marker | 'm'/esc: close map"
+	if m.editingNote {
+		helpText = "enter: save note | esc: cancel"
+	}
+	help = m.styles.Faint.Render(helpText)
+
+	side := lipgloss.JoinVertical(lipgloss.Left, m.renderLegend(), "", m.renderInspect())
+	sideView = m.styles.Panel.Width(fullMapPanelWidth).Render(side)
+
+	maxWidth, maxHeight := m.fullMapSize(sideView)
+	mapView = m.styles.MapBorder.Render(m.renderMapViewport(maxWidth, maxHeight, m.mapCursor))
+	return title, mapView, sideView, help
+}
+
+// fullMapSize is the room the full map leaves for the floor next to sideView.
+func (m model) fullMapSize(sideView string) (int, int) {
+	frame := m.styles.MapBorder.GetHorizontalFrameSize()
+	return m.width - frame - lipgloss.Width(sideView), m.height - frame - 2
+}
+
+// renderLegend lists the symbols that can appear on the map.
+func (m model) renderLegend() string {
+	entries := [][2]string{{"[@]", "You"}, {"[?]", "Unexplored Room"}}
+	for t := Empty; t <= Pit; t++ {
+		r := &room{Type: t, TrapDetected: true}
+		name := r.getRoomName()
+		if t == Empty {
+			name = "Empty Room"
+		}
+		entries = append(entries, [2]string{"[" + r.getRoomSymbol() + "]", name})
+	}
+	entries = append(entries,
+		[2]string{"[M]", "Wandering Monsters"},
+		[2]string{"[s]", "Lever"},
+		[2]string{" ■", "Locked Door"},
+		[2]string{strings.Join(roomMarkers[1:], ""), "Your Markers"},
+	)
+
+	lines := []string{m.styles.Title.Render("Legend")}
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("%-4s %s", e[0], e[1]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderInspect describes the room under the cursor. Only visited rooms
+// show their description.
+func (m model) renderInspect() string {
+	r := m.cursorRoom()
+	if r == nil {
+		return m.styles.Faint.Render("Nothing is known about this place.")
+	}
+
+	x, y := m.mapCursor.X, m.mapCursor.Y
+	var lines []string
+	if r.Visited {
+		lines = append(lines, m.styles.Title.Render(r.getRoomName()), m.describeRoom(x, y))
+		if m.floors[m.currentFloor].wandererAt(x, y) {
+			lines = append(lines, m.styles.Help.Render("Wandering monsters roam here."))
+		}
+	} else {
+		lines = append(lines, m.styles.Title.Render("Unexplored Room"), "You have not been here yet.")
+	}
+
+	if m.editingNote {
+		lines = append(lines, "", "Note: "+m.noteDraft+"_")
+	} else if r.Note != "" {
+		lines = append(lines, "", "Note: "+r.Note)
+	}
+	if r.Marker != "" {
+		lines = append(lines, "Marker: "+r.Marker)
+	}
+	return strings.Join(lines, "\n")
+}
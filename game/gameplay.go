@@ -2,10 +2,8 @@ package game
 
 import (
 	"fmt"
-	"math/rand"
-	"strings"
+	"image"
 
-	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -16,43 +14,103 @@ func (m model) updateGame(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
+	case travelStepMsg:
+		return m.travelStep(msg)
+	case tea.MouseMsg:
+		if m.showFullMap {
+			return m.clickFullMap(msg)
+		}
+		if p, ok := m.clickedRoom(msg); ok && isLeftClick(msg) {
+			return m.travelTo(p)
+		}
 	case tea.KeyMsg:
-		prevX, prevY := m.playerMapX, m.playerMapY
+		if m.traveling() {
+			m = m.stopTravel()
+			m.message = "You stop."
+			return m, nil
+		}
+		if m.showFullMap {
+			return m.updateFullMap(msg)
+		}
+
+		prevFloor, prevX, prevY := m.currentFloor, m.playerMapX, m.playerMapY
+		m.message = ""
+
+		if m.zoomed {
+			var handled bool
+			if m, handled = m.updateInterior(msg); handled {
+				if prevFloor == m.currentFloor && (prevX != m.playerMapX || prevY != m.playerMapY) {
+					m = m.enterRoom()
+					m = m.advanceClock(1, false)
+				}
+				if m.state == StateCombat {
+					return m.openCombat()
+				}
+				return m, nil
+			}
+		}
 
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
 		case "up", "w":
-			if m.playerMapY > 0 && currentMap[m.playerMapY-1][m.playerMapX] != nil {
-				m.playerMapY--
-				currentMap[m.playerMapY][m.playerMapX].Visited = true
-			}
+			m = m.movePlayer(North)
 		case "down", "s":
-			if m.playerMapY < len(currentMap)-1 && currentMap[m.playerMapY+1][m.playerMapX] != nil {
-				m.playerMapY++
-				currentMap[m.playerMapY][m.playerMapX].Visited = true
-			}
+			m = m.movePlayer(South)
 		case "left", "a":
-			if m.playerMapX > 0 && currentMap[m.playerMapY][m.playerMapX-1] != nil {
-				m.playerMapX--
-				currentMap[m.playerMapY][m.playerMapX].Visited = true
-			}
+			m = m.movePlayer(West)
 		case "right", "d":
-			if m.playerMapX < len(currentMap[0])-1 && currentMap[m.playerMapY][m.playerMapX+1] != nil {
-				m.playerMapX++
-				currentMap[m.playerMapY][m.playerMapX].Visited = true
+			m = m.movePlayer(East)
+		case "e":
+			m = m.searchRoom()
+		case "u":
+			m = m.readMapScroll()
+		case "m":
+			m.showFullMap = true
+			m.mapCursor = image.Point{X: m.playerMapX, Y: m.playerMapY}
+		case "z":
+			m = m.toggleZoom()
+		case "r":
+			m = m.rest()
+		case "o":
+			return m.autoexplore()
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if currentMap[m.playerMapY][m.playerMapX].Type == Shop {
+				m = m.buy(int(msg.String()[0] - '1'))
 			}
 		case "enter", "x":
 			currentRoom := currentMap[m.playerMapY][m.playerMapX]
+			if currentRoom.Switch && !currentRoom.SwitchOn {
+				m = m.activateSwitch(currentRoom)
+				break
+			}
 			switch currentRoom.Type {
+			case Trap:
+				m = m.disarmTrap(currentRoom)
+
+			case Teleporter:
+				m = m.teleport(currentRoom)
+
+			case Pit:
+				m = m.jumpIntoPit(currentRoom)
 
 			case StairsUp:
+				if f := m.floors[m.currentFloor]; f.boss != "" && !f.bossDefeated {
+					m.message = fmt.Sprintf("The stairs are sealed. Defeat the %s first.", BossTemplates[f.boss].Name)
+					break
+				}
+				if f := m.floors[m.currentFloor]; !f.objectiveDone() {
+					m.message = "The stairs are sealed. " + f.objectiveText()
+					break
+				}
+				m.floors[m.currentFloor].leftAt = m.clock
 				m.currentFloor++
 				if m.currentFloor >= len(m.floors) {
-					newFloor, startX, startY := generateMap(9, 9, 15, m.currentFloor)
-					m.floors = append(m.floors, *newFloor)
+					nextFloor, startX, startY := newFloor(m.runSeed, m.currentFloor)
+					m.floors = append(m.floors, *nextFloor)
 					m.playerMapX, m.playerMapY = startX, startY
 					m.floors[m.currentFloor].worldMap[startY][startX].Visited = true
+					m.revealAround()
 				} else {
 					for y, row := range m.floors[m.currentFloor].worldMap {
 						for x, room := range row {
@@ -62,9 +120,11 @@ func (m model) updateGame(msg tea.Msg) (tea.Model, tea.Cmd) {
 							}
 						}
 					}
+					m = m.arriveOnFloor()
 				}
 			case StairsDown:
 				if m.currentFloor > 0 {
+					m.floors[m.currentFloor].leftAt = m.clock
 					m.currentFloor--
 					for y, row := range m.floors[m.currentFloor].worldMap {
 						for x, room := range row {
@@ -74,128 +134,177 @@ func (m model) updateGame(msg tea.Msg) (tea.Model, tea.Cmd) {
 							}
 						}
 					}
+					m = m.arriveOnFloor()
 				}
 			}
 		}
 
-		if prevX != m.playerMapX || prevY != m.playerMapY {
-			newRoom := currentMap[m.playerMapY][m.playerMapX]
-			newRoom.Visited = true
+		if prevFloor == m.currentFloor && (prevX != m.playerMapX || prevY != m.playerMapY) {
+			m = m.enterRoom()
+			m = m.advanceClock(1, false)
+		}
+		if m.state == StateCombat {
+			return m.openCombat()
+		}
+	}
+	return m, nil
+}
 
-			if newRoom.Type == Enemy {
-				m.state = StateCombat
+// enterRoom resolves whatever waits in the room the player just walked into.
+// When zoomed in, chests and enemies wait until the player reaches them.
+func (m model) enterRoom() model {
+	newRoom := m.floors[m.currentFloor].worldMap[m.playerMapY][m.playerMapX]
+	newRoom.Visited = true
+	m.revealAround()
 
-				var playerAttacks []Attack
-				for _, attacks := range AttackTemplates {
-					playerAttacks = append(playerAttacks, attacks)
-				}
-				var playerMagics []Magic
-				for _, magics := range MagicTemplates {
-					playerMagics = append(playerMagics, magics)
-				}
+	if newRoom.HasKey {
+		newRoom.HasKey = false
+		m.player.inventory["key"]++
+		m.message = "You found a key!"
+	}
+	if newRoom.HasRune {
+		newRoom.HasRune = false
+		m.player.inventory["rune"]++
+		m.message = "You found the rune! The stairs are open."
+	}
+	m.floors[m.currentFloor].updateObjective()
 
-				if m.player.inventory == nil {
-					m.player.inventory = make(map[string]int)
-				}
-				m.player.inventory["potion"] = 1
+	if m.zoomed && newRoom.hasPendingContents() {
+		return m
+	}
+	return m.resolveRoom(newRoom)
+}
 
-				playerEntity := &Player{
-					data:    &m.player,
-					Attacks: playerAttacks,
-					Magics:  playerMagics,
-				}
-				numEnemies := 1 + rand.Intn(3)
-				enemies := make([]*Foe, numEnemies)
-				for i := range enemies {
-					enemies[i] = newGoblin()
-				}
+func (m model) resolveRoom(r *room) model {
+	switch r.Type {
+	case Tresure:
+		m.message = m.openChest(r)
+		r.Type = Empty
+		r.clear(Tresure, m.floors[m.currentFloor].turn)
+	case Trap:
+		m = m.enterTrapRoom(r)
+	case Boss:
+		m = m.startBossCombat(m.floors[m.currentFloor].boss)
+	case Enemy:
+		m = m.startCombat(m.roomEnemies(), false)
+		r.Type = Empty
+		r.clear(Enemy, m.floors[m.currentFloor].turn)
+	}
+	return m
+}
 
-				turnOrder := calculateTurnOrder(playerEntity, enemies)
-
-				enemyProgressBar := progress.New(
-					progress.WithGradient(string(indigo), string(orange)),
-					progress.WithoutPercentage(),
-				)
-
-				m.combat = &CombatState{
-					player:                playerEntity,
-					enemies:               enemies,
-					turnOrder:             turnOrder,
-					turnIndex:             0,
-					actionState:           ActionSelect,
-					isEnemyTurnInProgress: false,
-					enemyActionProgress:   enemyProgressBar,
-				}
+// movePlayer walks through the exit in dir, using a key on locked doors.
+func (m model) movePlayer(dir direction) model {
+	currentMap := m.floors[m.currentFloor].worldMap
+	exit := currentMap[m.playerMapY][m.playerMapX].Exits[dir]
+	if exit == nil {
+		return m
+	}
+	next := currentMap[m.playerMapY+cardinalDirections[dir].Y][m.playerMapX+cardinalDirections[dir].X]
+	if next.Hidden {
+		return m
+	}
 
-				newRoom.Type = Empty
-			}
+	if exit.Locked {
+		if m.player.inventory["key"] <= 0 {
+			m.message = "The door is locked. You need a key."
+			return m
+		}
+		m.player.inventory["key"]--
+		if m.player.inventory["key"] == 0 {
+			delete(m.player.inventory, "key")
 		}
+		exit.Locked = false
+		m.message = "You unlock the door with a key."
 	}
-	return m, nil
+
+	m.playerMapX += cardinalDirections[dir].X
+	m.playerMapY += cardinalDirections[dir].Y
+	return m
 }
 
 func (m model) renderGameView() string {
 	currentMap := m.floors[m.currentFloor].worldMap
 	currentRoom := currentMap[m.playerMapY][m.playerMapX]
 
-	emptyCell := lipgloss.NewStyle().Width(3).SetString(" ")
-
-	var mapRows []string
-	for y, row := range currentMap {
-		var mapRow strings.Builder
-		for x, room := range row {
-			if x == m.playerMapX && y == m.playerMapY {
-				mapRow.WriteString(m.styles.Player.String())
-			} else if room != nil {
-				var symbol string
-				if room.Visited {
-					symbol = room.getRoomSymbol()
-				} else {
-					symbol = "?"
-				}
-
-				style := m.styles.Room
-				if room.Type == Tresure || room.Type == Shop || room.Type == StairsUp {
-					style = style.Inherit(m.styles.RoomSpecial)
-				}
-				mapRow.WriteString(style.Render(fmt.Sprintf("[%s]", symbol)))
-			} else {
-				mapRow.WriteString(emptyCell.String())
-			}
-		}
-		mapRows = append(mapRows, mapRow.String())
+	if m.showFullMap {
+		return m.renderFullMap()
 	}
 
-	mapContent := lipgloss.JoinVertical(lipgloss.Center, mapRows...)
-	mapView := m.styles.MapBorder.Width(45).Align(lipgloss.Center).Render(mapContent)
+	// The map gets whatever the side panels and the help line leave free.
+	mapWidth, mapHeight := m.mainMapSize()
+	mapContent := m.renderMapViewport(mapWidth, mapHeight, image.Point{X: m.playerMapX, Y: m.playerMapY})
+	if m.zoomed {
+		mapContent = m.renderInterior()
+	}
+	mapView := m.styles.MapBorder.Width(max(min(45, mapWidth), lipgloss.Width(mapContent))).Align(lipgloss.Center).Render(mapContent)
 
 	//mapHeight := lipgloss.Height(mapView)
 	cameraWidth := m.width - lipgloss.Width(mapView) - 4
 
 	statsArt := m.styles.StatsArt.Render(playerArt)
 	statsText := fmt.Sprintf(
-		"HP: %d\nMana: %d\nSpeed: %d\nMagic: %d\nStrength: %d \nDefense: %d",
+		"HP: %d\nMana: %d\nSpeed: %d\nMagic: %d\nStrength: %d \nDefense: %d\nGold: %d\nTurn: %d",
 		m.player.stats.hp,
 		m.player.stats.mana,
 		m.player.stats.speed,
 		m.player.stats.magic,
 		m.player.stats.strength,
 		m.player.stats.defense,
+		m.player.gold,
+		m.floors[m.currentFloor].turn,
 	)
 	statsContent := lipgloss.JoinHorizontal(lipgloss.Top, statsArt, statsText)
 	statsView := m.styles.Panel.Width(cameraWidth).Render(statsContent)
 
-	cameraHeight := 2
+	cameraHeight := 3
 
-	cameraContent := fmt.Sprintf("%s", currentRoom.getRoomDescription())
+	cameraContent := m.describeRoom(m.playerMapX, m.playerMapY)
+	if currentRoom.Type == Shop {
+		cameraContent += "\n" + m.renderStock(currentRoom)
+	}
+	if text := m.floors[m.currentFloor].objectiveText(); text != "" {
+		cameraContent += "\n" + m.styles.Help.Render(text)
+	}
+	if currentRoom.Note != "" {
+		cameraContent += "\n" + m.styles.Faint.Render("Note: "+currentRoom.Note)
+	}
+	if m.message != "" {
+		cameraContent += "\n" + m.styles.Help.Render(m.message)
+	}
 	cameraView := m.styles.Panel.Width(cameraWidth).Height(cameraHeight).Render(cameraContent)
 
 	leftPanel := lipgloss.JoinVertical(lipgloss.Left, cameraView, statsView)
 
-	helpText := "Arrows/wasd: move | 'q': quit"
+	floorName := fmt.Sprintf("Floor %d", m.currentFloor)
+	if theme, ok := ThemeTemplates[m.floors[m.currentFloor].theme]; ok {
+		floorName += " (" + theme.Name + ")"
+	}
+	helpText := fmt.Sprintf("%s | Seed %d | Arrows/wasd: move | 'o': explore | 'e': search | 'm': map | 'z': zoom | 'q': quit", floorName, m.runSeed)
 	if currentRoom.Type == StairsUp || currentRoom.Type == StairsDown {
 		helpText += " | 'enter'/'x': Use Stairs"
 	}
+	if currentRoom.Type == Trap && currentRoom.TrapDetected {
+		helpText += " | 'enter'/'x': Disarm"
+	}
+	if currentRoom.Type == Teleporter {
+		helpText += " | 'enter'/'x': Teleport"
+	}
+	if currentRoom.Switch && !currentRoom.SwitchOn {
+		helpText += " | 'enter'/'x': Pull Lever"
+	}
+	if currentRoom.Type == Pit {
+		helpText += " | 'enter'/'x': Jump In"
+	}
+	if currentRoom.Type == Empty {
+		helpText += " | 'r': Rest"
+	}
+	if currentRoom.Type == Shop {
+		helpText += " | '1-9': Buy"
+	}
+	if m.player.inventory["map_scroll"] > 0 {
+		helpText += " | 'u': Read Map"
+	}
 	help := m.styles.Faint.Padding(0, 1).Render(helpText)
 
 	mainView := lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, mapView)
@@ -204,6 +313,32 @@ func (m model) renderGameView() string {
 	return finalView
 }
 
+// renderPassage draws the link leaving the room at x, y towards dir: a line
+// for an open passage, a block for a locked door and blank space otherwise.
+// Only passages of visited rooms are known unless the floor is mapped;
+// passages into undiscovered secret rooms stay blank.
+func (m model) renderPassage(worldMap [][]*room, x, y int, dir direction) string {
+	r := worldMap[y][x]
+	if r == nil || r.Hidden || r.Exits[dir] == nil {
+		return " "
+	}
+	offset := cardinalDirections[dir]
+	neighbor := worldMap[y+offset.Y][x+offset.X]
+	if neighbor.Hidden || !r.Seen || !neighbor.Seen {
+		return " "
+	}
+	if !r.Visited && !neighbor.Visited && !m.floors[m.currentFloor].mapped {
+		return " "
+	}
+	if r.Exits[dir].Locked {
+		return m.styles.Locked.Render("■")
+	}
+	if dir == East {
+		return m.styles.Faint.Render("─")
+	}
+	return m.styles.Faint.Render("│")
+}
+
 func (r *room) getRoomSymbol() string {
 	switch r.Type {
 	case Empty:
@@ -218,11 +353,45 @@ func (r *room) getRoomSymbol() string {
 		return "▲"
 	case StairsDown:
 		return "▼"
+	case Trap:
+		return "^"
+	case Boss:
+		return "B"
+	case Teleporter:
+		return "◊"
+	case Pit:
+		return "●"
 	default:
 		return "?"
 	}
 }
 
+func (r *room) getRoomName() string {
+	switch r.Type {
+	case Enemy:
+		return "Monster Lair"
+	case Tresure:
+		return "Treasure Room"
+	case Shop:
+		return "Shop"
+	case StairsUp:
+		return "Stairs Up"
+	case StairsDown:
+		return "Stairs Down"
+	case Trap:
+		if r.TrapDetected {
+			return "Trapped Room"
+		}
+	case Boss:
+		return "Boss Chamber"
+	case Teleporter:
+		return "Teleporter"
+	case Pit:
+		return "Pit"
+	}
+	return "Room"
+}
+
 func (r *room) getRoomDescription() string {
 	switch r.Type {
 	case Empty:
@@ -237,6 +406,17 @@ func (r *room) getRoomDescription() string {
 		return "Some stone stairs, they take you to the darkness\nYou wanna go up?"
 	case StairsDown:
 		return "You can go down again"
+	case Trap:
+		if trap, ok := TrapTemplates[r.Trap]; ok && r.TrapDetected {
+			return fmt.Sprintf("%s\n%s", trap.Name, trap.Description)
+		}
+		return "Something about this room feels wrong..."
+	case Boss:
+		return "A heavy silence fills this room.\nSomething powerful guards the way up."
+	case Teleporter:
+		return "Runes glow on a circle carved in the floor.\nStep in to travel far away."
+	case Pit:
+		return "A dark shaft drops into the depths.\nJump in? There is no way back up."
 	default:
 		return "Unknown room type."
 	}
@@ -0,0 +1,462 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+	"sort"
+)
+
+// mapGenerator carves the rooms of a floor and returns where the player
+// starts. Room types are assigned afterwards by generateMap, so every
+// algorithm produces the same kind of floor.
+type mapGenerator interface {
+	carve(rng *rand.Rand, width, height, maxRooms int) ([][]*room, image.Point)
+}
+
+var mapGenerators = map[string]mapGenerator{
+	"drunkard": drunkardWalk{},
+	"bsp":      bspGenerator{},
+	"caverns":  cavernGenerator{},
+	"ring":     ringGenerator{},
+}
+
+// generatorNames lists mapGenerators in a stable order so seeded picks are
+// reproducible.
+var generatorNames = []string{"drunkard", "bsp", "caverns", "ring"}
+
+// generatorForFloor picks one of the generators allowed by the floor
+// parameters, or any generator when none are listed.
+func generatorForFloor(seed int64, params FloorParams) mapGenerator {
+	names := params.Generators
+	if len(names) == 0 {
+		names = generatorNames
+	}
+	rng := rand.New(rand.NewSource(seed))
+	return mapGenerators[names[rng.Intn(len(names))]]
+}
+
+func newWorldMap(width, height int) [][]*room {
+	worldMap := make([][]*room, height)
+	for i := range worldMap {
+		worldMap[i] = make([]*room, width)
+	}
+	return worldMap
+}
+
+func inBounds(worldMap [][]*room, x, y int) bool {
+	return y >= 0 && y < len(worldMap) && x >= 0 && x < len(worldMap[0])
+}
+
+// cardinalDirections holds the grid offset of each direction.
+var cardinalDirections = []image.Point{North: {0, -1}, South: {0, 1}, West: {-1, 0}, East: {1, 0}}
+
+func countNeighbors(worldMap [][]*room, x, y int) int {
+	count := 0
+	for _, exit := range worldMap[y][x].Exits {
+		if exit != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// connectRooms opens a passage between two neighboring rooms.
+func connectRooms(worldMap [][]*room, a, b image.Point) {
+	for dir, offset := range cardinalDirections {
+		if a.Add(offset) != b {
+			continue
+		}
+		from, to := worldMap[a.Y][a.X], worldMap[b.Y][b.X]
+		if from.Exits[dir] == nil {
+			p := &passage{}
+			from.Exits[dir] = p
+			to.Exits[direction(dir).opposite()] = p
+		}
+		return
+	}
+}
+
+// removeRoom deletes a room and closes the passages that led into it.
+func removeRoom(worldMap [][]*room, p image.Point) {
+	for dir, exit := range worldMap[p.Y][p.X].Exits {
+		if exit == nil {
+			continue
+		}
+		n := p.Add(cardinalDirections[dir])
+		worldMap[n.Y][n.X].Exits[direction(dir).opposite()] = nil
+	}
+	worldMap[p.Y][p.X] = nil
+}
+
+// pruneRooms removes rooms until the floor has at most maxRooms, preferring
+// the ones with fewer neighbors and never splitting the floor in two.
+func pruneRooms(rng *rand.Rand, worldMap [][]*room, maxRooms int, keep image.Point) {
+	for {
+		coords := roomCoords(worldMap)
+		if len(coords) <= maxRooms {
+			return
+		}
+
+		rng.Shuffle(len(coords), func(i, j int) {
+			coords[i], coords[j] = coords[j], coords[i]
+		})
+		sort.SliceStable(coords, func(i, j int) bool {
+			return countNeighbors(worldMap, coords[i].X, coords[i].Y) < countNeighbors(worldMap, coords[j].X, coords[j].Y)
+		})
+
+		removed := false
+		for _, coord := range coords {
+			if coord == keep {
+				continue
+			}
+			r := worldMap[coord.Y][coord.X]
+			worldMap[coord.Y][coord.X] = nil
+			connected := len(reachableFrom(worldMap, keep, true)) == len(coords)-1
+			worldMap[coord.Y][coord.X] = r
+			if connected {
+				removeRoom(worldMap, coord)
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			return
+		}
+	}
+}
+
+// reachableFrom returns every room that can be walked to from start,
+// teleporting between linked teleporters on the way. Locked passages are
+// only crossed when throughLocked is set.
+func reachableFrom(worldMap [][]*room, start image.Point, throughLocked bool) map[image.Point]bool {
+	seen := map[image.Point]bool{start: true}
+	queue := []image.Point{start}
+	visit := func(next image.Point) {
+		if inBounds(worldMap, next.X, next.Y) && worldMap[next.Y][next.X] != nil && !seen[next] {
+			seen[next] = true
+			queue = append(queue, next)
+		}
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		r := worldMap[current.Y][current.X]
+		for dir, exit := range r.Exits {
+			if exit == nil || (exit.Locked && !throughLocked) {
+				continue
+			}
+			visit(current.Add(cardinalDirections[dir]))
+		}
+		if r.Type == Teleporter {
+			visit(r.Link)
+		}
+	}
+	return seen
+}
+
+// connectNeighbors opens a passage between every pair of orthogonally
+// adjacent rooms, for open areas such as chambers and caves.
+func connectNeighbors(worldMap [][]*room) {
+	for _, coord := range roomCoords(worldMap) {
+		for _, dir := range []direction{South, East} {
+			next := coord.Add(cardinalDirections[dir])
+			if inBounds(worldMap, next.X, next.Y) && worldMap[next.Y][next.X] != nil {
+				connectRooms(worldMap, coord, next)
+			}
+		}
+	}
+}
+
+// drunkardWalk wanders from the center of the grid, leaving a room on every
+// cell it steps on.
+type drunkardWalk struct{}
+
+func (drunkardWalk) carve(rng *rand.Rand, width, height, maxRooms int) ([][]*room, image.Point) {
+	worldMap := newWorldMap(width, height)
+	start := image.Point{X: width / 2, Y: height / 2}
+	worldMap[start.Y][start.X] = &room{}
+	current := start
+	roomsCreated := 1
+
+	for roomsCreated < maxRooms {
+		next := current.Add(cardinalDirections[rng.Intn(len(cardinalDirections))])
+		if !inBounds(worldMap, next.X, next.Y) {
+			continue
+		}
+		if worldMap[next.Y][next.X] == nil {
+			worldMap[next.Y][next.X] = &room{}
+			roomsCreated++
+		}
+		connectRooms(worldMap, current, next)
+		current = next
+	}
+
+	return worldMap, start
+}
+
+// bspGenerator splits the grid into partitions, places a small chamber in
+// each leaf and joins sibling partitions with corridors.
+type bspGenerator struct{}
+
+const bspMinLeaf = 3
+
+func (bspGenerator) carve(rng *rand.Rand, width, height, maxRooms int) ([][]*room, image.Point) {
+	worldMap := newWorldMap(width, height)
+	root := image.Rect(0, 0, width, height)
+	center := bspSplit(rng, worldMap, root)
+
+	start := center
+	pruneRooms(rng, worldMap, maxRooms, start)
+	return worldMap, start
+}
+
+// bspSplit carves the partition and returns a room inside it that its parent
+// can connect to.
+func bspSplit(rng *rand.Rand, worldMap [][]*room, area image.Rectangle) image.Point {
+	canSplitX := area.Dx() >= bspMinLeaf*2
+	canSplitY := area.Dy() >= bspMinLeaf*2
+
+	if !canSplitX && !canSplitY {
+		return bspChamber(rng, worldMap, area)
+	}
+
+	splitX := canSplitX && (!canSplitY || area.Dx() > area.Dy() || (area.Dx() == area.Dy() && rng.Intn(2) == 0))
+
+	var first, second image.Rectangle
+	if splitX {
+		cut := area.Min.X + bspMinLeaf + rng.Intn(area.Dx()-bspMinLeaf*2+1)
+		first = image.Rect(area.Min.X, area.Min.Y, cut, area.Max.Y)
+		second = image.Rect(cut, area.Min.Y, area.Max.X, area.Max.Y)
+	} else {
+		cut := area.Min.Y + bspMinLeaf + rng.Intn(area.Dy()-bspMinLeaf*2+1)
+		first = image.Rect(area.Min.X, area.Min.Y, area.Max.X, cut)
+		second = image.Rect(area.Min.X, cut, area.Max.X, area.Max.Y)
+	}
+
+	a := bspSplit(rng, worldMap, first)
+	b := bspSplit(rng, worldMap, second)
+	carveCorridor(rng, worldMap, a, b)
+
+	if rng.Intn(2) == 0 {
+		return a
+	}
+	return b
+}
+
+func bspChamber(rng *rand.Rand, worldMap [][]*room, area image.Rectangle) image.Point {
+	w := 1 + rng.Intn(min(2, area.Dx()))
+	h := 1 + rng.Intn(min(2, area.Dy()))
+	x0 := area.Min.X + rng.Intn(area.Dx()-w+1)
+	y0 := area.Min.Y + rng.Intn(area.Dy()-h+1)
+
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			if worldMap[y][x] == nil {
+				worldMap[y][x] = &room{}
+			}
+			if x > x0 {
+				connectRooms(worldMap, image.Point{X: x - 1, Y: y}, image.Point{X: x, Y: y})
+			}
+			if y > y0 {
+				connectRooms(worldMap, image.Point{X: x, Y: y - 1}, image.Point{X: x, Y: y})
+			}
+		}
+	}
+	return image.Point{X: x0 + rng.Intn(w), Y: y0 + rng.Intn(h)}
+}
+
+// carveCorridor joins two rooms with an L-shaped line of rooms.
+func carveCorridor(rng *rand.Rand, worldMap [][]*room, from, to image.Point) {
+	horizontalFirst := rng.Intn(2) == 0
+	current := from
+	for current != to {
+		next := current
+		moveX := current.X != to.X && (horizontalFirst || current.Y == to.Y)
+		if moveX {
+			next.X += sign(to.X - current.X)
+		} else {
+			next.Y += sign(to.Y - current.Y)
+		}
+		if worldMap[next.Y][next.X] == nil {
+			worldMap[next.Y][next.X] = &room{}
+		}
+		connectRooms(worldMap, current, next)
+		current = next
+	}
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+// cavernGenerator runs a cellular automaton over random noise and keeps the
+// largest open region as the floor.
+type cavernGenerator struct{}
+
+const (
+	cavernFillChance = 0.45
+	cavernSteps      = 4
+	cavernAttempts   = 10
+)
+
+func (cavernGenerator) carve(rng *rand.Rand, width, height, maxRooms int) ([][]*room, image.Point) {
+	for range cavernAttempts {
+		open := make([][]bool, height)
+		for y := range open {
+			open[y] = make([]bool, width)
+			for x := range open[y] {
+				open[y][x] = rng.Float64() >= cavernFillChance
+			}
+		}
+
+		for range cavernSteps {
+			open = cavernStep(open)
+		}
+
+		region := largestRegion(open)
+		if len(region) < maxRooms/2 {
+			continue
+		}
+
+		worldMap := newWorldMap(width, height)
+		for _, coord := range region {
+			worldMap[coord.Y][coord.X] = &room{}
+		}
+		connectNeighbors(worldMap)
+
+		center := image.Point{X: width / 2, Y: height / 2}
+		sort.Slice(region, func(i, j int) bool {
+			return manhattan(region[i], center) < manhattan(region[j], center)
+		})
+		start := region[0]
+
+		pruneRooms(rng, worldMap, maxRooms, start)
+		return worldMap, start
+	}
+
+	return drunkardWalk{}.carve(rng, width, height, maxRooms)
+}
+
+func cavernStep(open [][]bool) [][]bool {
+	height, width := len(open), len(open[0])
+	next := make([][]bool, height)
+	for y := range next {
+		next[y] = make([]bool, width)
+		for x := range next[y] {
+			walls := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || nx >= width || ny < 0 || ny >= height || !open[ny][nx] {
+						walls++
+					}
+				}
+			}
+			next[y][x] = walls < 5
+		}
+	}
+	return next
+}
+
+// largestRegion returns the cells of the biggest orthogonally connected
+// group of open cells.
+func largestRegion(open [][]bool) []image.Point {
+	height, width := len(open), len(open[0])
+	seen := make([][]bool, height)
+	for y := range seen {
+		seen[y] = make([]bool, width)
+	}
+
+	var best []image.Point
+	for y := range open {
+		for x := range open[y] {
+			if !open[y][x] || seen[y][x] {
+				continue
+			}
+
+			region := []image.Point{{X: x, Y: y}}
+			seen[y][x] = true
+			for i := 0; i < len(region); i++ {
+				for _, dir := range cardinalDirections {
+					n := region[i].Add(dir)
+					if n.X >= 0 && n.X < width && n.Y >= 0 && n.Y < height && open[n.Y][n.X] && !seen[n.Y][n.X] {
+						seen[n.Y][n.X] = true
+						region = append(region, n)
+					}
+				}
+			}
+
+			if len(region) > len(best) {
+				best = region
+			}
+		}
+	}
+	return best
+}
+
+func manhattan(a, b image.Point) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y)
+}
+
+// ringGenerator builds a loop of rooms around the middle of the grid with
+// short dead-end branches hanging off it.
+type ringGenerator struct{}
+
+func (ringGenerator) carve(rng *rand.Rand, width, height, maxRooms int) ([][]*room, image.Point) {
+	worldMap := newWorldMap(width, height)
+
+	// The loop takes around three quarters of the rooms; the rest become
+	// branches.
+	perimeter := max(8, maxRooms*3/4)
+	ringW := min(width, max(3, perimeter/4+1+rng.Intn(2)))
+	ringH := min(height, max(3, (perimeter+4)/2-ringW))
+	x0 := (width - ringW) / 2
+	y0 := (height - ringH) / 2
+
+	var ring []image.Point
+	for x := x0; x < x0+ringW; x++ {
+		ring = append(ring, image.Point{X: x, Y: y0})
+	}
+	for y := y0 + 1; y < y0+ringH; y++ {
+		ring = append(ring, image.Point{X: x0 + ringW - 1, Y: y})
+	}
+	for x := x0 + ringW - 2; x >= x0; x-- {
+		ring = append(ring, image.Point{X: x, Y: y0 + ringH - 1})
+	}
+	for y := y0 + ringH - 2; y > y0; y-- {
+		ring = append(ring, image.Point{X: x0, Y: y})
+	}
+
+	roomsCreated := 0
+	for _, coord := range ring {
+		worldMap[coord.Y][coord.X] = &room{}
+		roomsCreated++
+	}
+	for i, coord := range ring {
+		connectRooms(worldMap, coord, ring[(i+1)%len(ring)])
+	}
+
+	for attempts := 0; roomsCreated < maxRooms && attempts < maxRooms*4; attempts++ {
+		current := ring[rng.Intn(len(ring))]
+		dir := cardinalDirections[rng.Intn(len(cardinalDirections))]
+		length := 1 + rng.Intn(2)
+		for range length {
+			next := current.Add(dir)
+			if !inBounds(worldMap, next.X, next.Y) || worldMap[next.Y][next.X] != nil || roomsCreated >= maxRooms {
+				break
+			}
+			worldMap[next.Y][next.X] = &room{}
+			roomsCreated++
+			connectRooms(worldMap, current, next)
+			current = next
+		}
+	}
+
+	return worldMap, ring[rng.Intn(len(ring))]
+}
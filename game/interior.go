@@ -0,0 +1,354 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Interiors are odd-sized so doors and the main feature sit on the middle
+// row and column.
+const (
+	interiorWidth  = 11
+	interiorHeight = 7
+)
+
+type tile int
+
+const (
+	tileFloor tile = iota
+	tileWall
+	tileDoor
+	tileLockedDoor
+	tileTable
+	tileBarrel
+	tileChest
+	tileStairsUp
+	tileStairsDown
+	tileTrap
+	tileMerchant
+	tileBoss
+	tileTeleporter
+	tilePit
+)
+
+// interior is the tile grid of a single room. It is rebuilt from the room
+// seed whenever it is needed, so only the room itself keeps state.
+type interior struct {
+	tiles    [interiorHeight][interiorWidth]tile
+	enemies  []image.Point
+	enemyIDs []string
+}
+
+var interiorCenter = image.Point{X: interiorWidth / 2, Y: interiorHeight / 2}
+
+// doorTile is where the exit towards dir sits on the room wall.
+func doorTile(dir direction) image.Point {
+	switch dir {
+	case North:
+		return image.Point{X: interiorCenter.X, Y: 0}
+	case South:
+		return image.Point{X: interiorCenter.X, Y: interiorHeight - 1}
+	case West:
+		return image.Point{X: 0, Y: interiorCenter.Y}
+	default:
+		return image.Point{X: interiorWidth - 1, Y: interiorCenter.Y}
+	}
+}
+
+// roomEnemyIDs picks the one to three enemies waiting in an enemy room,
+// unless the room has its own group.
+func roomEnemyIDs(r *room, seed int64, pool []string) []string {
+	if len(r.Enemies) > 0 {
+		return r.Enemies
+	}
+	rng := rand.New(rand.NewSource(seed))
+	ids := make([]string, 1+rng.Intn(3))
+	for i := range ids {
+		ids[i] = pool[rng.Intn(len(pool))]
+	}
+	return ids
+}
+
+func (m model) roomEnemies() []*Foe {
+	f := m.floors[m.currentFloor]
+	seed := roomSeed(f.seed, m.playerMapX, m.playerMapY)
+	var enemies []*Foe
+	for _, id := range roomEnemyIDs(f.worldMap[m.playerMapY][m.playerMapX], seed, m.enemyPool()) {
+		enemies = append(enemies, newFoe(id))
+	}
+	return enemies
+}
+
+// hasPendingContents reports whether the room holds something that waits
+// for the player to reach it when rooms are zoomed in.
+func (r *room) hasPendingContents() bool {
+	return r.Type == Tresure || r.Type == Enemy || r.Type == Boss
+}
+
+func (m model) currentInterior() interior {
+	f := m.floors[m.currentFloor]
+	return generateInterior(f.worldMap, m.playerMapX, m.playerMapY, roomSeed(f.seed, m.playerMapX, m.playerMapY), m.enemyPool())
+}
+
+// generateInterior lays out the room at x, y. The middle row and column are
+// always clear, so every door leads to the center of the room.
+func generateInterior(worldMap [][]*room, x, y int, seed int64, enemyPool []string) interior {
+	r := worldMap[y][x]
+	rng := rand.New(rand.NewSource(seed))
+
+	var in interior
+	for ty := range interiorHeight {
+		for tx := range interiorWidth {
+			if tx == 0 || ty == 0 || tx == interiorWidth-1 || ty == interiorHeight-1 {
+				in.tiles[ty][tx] = tileWall
+			}
+		}
+	}
+
+	for dir, exit := range r.Exits {
+		if exit == nil {
+			continue
+		}
+		if worldMap[y+cardinalDirections[dir].Y][x+cardinalDirections[dir].X].Hidden {
+			continue
+		}
+		door := doorTile(direction(dir))
+		in.tiles[door.Y][door.X] = tileDoor
+		if exit.Locked {
+			in.tiles[door.Y][door.X] = tileLockedDoor
+		}
+	}
+
+	var spots []image.Point
+	for ty := 1; ty < interiorHeight-1; ty++ {
+		for tx := 1; tx < interiorWidth-1; tx++ {
+			if tx != interiorCenter.X && ty != interiorCenter.Y {
+				spots = append(spots, image.Point{X: tx, Y: ty})
+			}
+		}
+	}
+	rng.Shuffle(len(spots), func(i, j int) { spots[i], spots[j] = spots[j], spots[i] })
+	for _, spot := range spots[:2+rng.Intn(3)] {
+		in.tiles[spot.Y][spot.X] = tileTable
+		if rng.Intn(2) == 0 {
+			in.tiles[spot.Y][spot.X] = tileBarrel
+		}
+	}
+
+	center := &in.tiles[interiorCenter.Y][interiorCenter.X]
+	switch r.Type {
+	case Tresure:
+		*center = tileChest
+	case StairsUp:
+		*center = tileStairsUp
+	case StairsDown:
+		*center = tileStairsDown
+	case Shop:
+		*center = tileMerchant
+	case Boss:
+		*center = tileBoss
+	case Teleporter:
+		*center = tileTeleporter
+	case Pit:
+		*center = tilePit
+	case Trap:
+		if r.TrapDetected {
+			*center = tileTrap
+		}
+	case Enemy:
+		slots := []image.Point{
+			interiorCenter,
+			interiorCenter.Add(image.Point{X: -2}),
+			interiorCenter.Add(image.Point{X: 2}),
+		}
+		in.enemyIDs = roomEnemyIDs(r, seed, enemyPool)
+		in.enemies = slots[:len(in.enemyIDs)]
+	}
+	return in
+}
+
+func (in interior) enemyAt(p image.Point) bool {
+	return in.enemyIndex(p) >= 0
+}
+
+func (in interior) enemyIndex(p image.Point) int {
+	for i, e := range in.enemies {
+		if e == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func (in interior) walkable(p image.Point) bool {
+	if p.X <= 0 || p.Y <= 0 || p.X >= interiorWidth-1 || p.Y >= interiorHeight-1 {
+		return false
+	}
+	if in.enemyAt(p) {
+		return false
+	}
+	switch in.tiles[p.Y][p.X] {
+	case tileFloor, tileChest, tileStairsUp, tileStairsDown, tileTrap, tileTeleporter, tilePit:
+		return true
+	}
+	return false
+}
+
+// snap returns p if the player can stand there, or the closest tile where
+// they can. Used after stairs and teleports, which do not go through a door.
+func (in interior) snap(p image.Point) image.Point {
+	if in.walkable(p) {
+		return p
+	}
+	for dist := 1; dist < interiorWidth+interiorHeight; dist++ {
+		for ty := range interiorHeight {
+			for tx := range interiorWidth {
+				q := image.Point{X: tx, Y: ty}
+				if manhattan(p, q) == dist && in.walkable(q) {
+					return q
+				}
+			}
+		}
+	}
+	return interiorCenter
+}
+
+// nextToDanger reports whether an enemy or the boss is beside p.
+func (in interior) nextToDanger(p image.Point) bool {
+	for _, offset := range cardinalDirections {
+		q := p.Add(offset)
+		if in.enemyAt(q) || in.tiles[q.Y][q.X] == tileBoss {
+			return true
+		}
+	}
+	return false
+}
+
+// moveInRoom walks one tile inside the current room. Walking into a door
+// leaves through it, arriving just inside the matching door of the next room.
+func (m model) moveInRoom(dir direction) model {
+	in := m.currentInterior()
+	pos := in.snap(image.Point{X: m.tileX, Y: m.tileY})
+	next := pos.Add(cardinalDirections[dir])
+
+	switch in.tiles[next.Y][next.X] {
+	case tileDoor, tileLockedDoor:
+		prevX, prevY := m.playerMapX, m.playerMapY
+		m = m.movePlayer(dir)
+		if m.playerMapX != prevX || m.playerMapY != prevY {
+			arrival := doorTile(dir.opposite()).Add(cardinalDirections[dir])
+			m.tileX, m.tileY = arrival.X, arrival.Y
+		}
+		return m
+	}
+
+	if !in.walkable(next) {
+		m.tileX, m.tileY = pos.X, pos.Y
+		return m
+	}
+	m.tileX, m.tileY = next.X, next.Y
+
+	currentRoom := m.floors[m.currentFloor].worldMap[m.playerMapY][m.playerMapX]
+	if in.tiles[next.Y][next.X] == tileChest || in.nextToDanger(next) {
+		m = m.resolveRoom(currentRoom)
+	}
+	return m
+}
+
+// toggleZoom switches between the floor map and the inside of the current
+// room. Leaving the room view resolves whatever was still waiting in it.
+func (m model) toggleZoom() model {
+	m.zoomed = !m.zoomed
+	if m.zoomed {
+		pos := m.currentInterior().snap(interiorCenter.Add(image.Point{Y: 1}))
+		m.tileX, m.tileY = pos.X, pos.Y
+		return m
+	}
+
+	currentRoom := m.floors[m.currentFloor].worldMap[m.playerMapY][m.playerMapX]
+	if currentRoom.hasPendingContents() {
+		m = m.resolveRoom(currentRoom)
+	}
+	return m
+}
+
+func (m model) updateInterior(msg tea.KeyMsg) (model, bool) {
+	switch msg.String() {
+	case "up", "w":
+		return m.moveInRoom(North), true
+	case "down", "s":
+		return m.moveInRoom(South), true
+	case "left", "a":
+		return m.moveInRoom(West), true
+	case "right", "d":
+		return m.moveInRoom(East), true
+	}
+	return m, false
+}
+
+func (m model) renderInterior() string {
+	in := m.currentInterior()
+	player := in.snap(image.Point{X: m.tileX, Y: m.tileY})
+
+	var rows []string
+	for ty := range interiorHeight {
+		var row strings.Builder
+		for tx := range interiorWidth {
+			p := image.Point{X: tx, Y: ty}
+			switch {
+			case p == player:
+				row.WriteString(m.styles.Help.Bold(true).Render("@"))
+			case in.enemyAt(p):
+				first, _ := utf8.DecodeRuneInString(EnemyTemplates[in.enemyIDs[in.enemyIndex(p)]].Name)
+				row.WriteString(m.styles.Locked.Render(string(unicode.ToLower(first))))
+			default:
+				row.WriteString(m.renderTile(in.tiles[ty][tx]))
+			}
+			if tx < interiorWidth-1 {
+				row.WriteString(" ")
+			}
+		}
+		rows = append(rows, row.String())
+	}
+
+	title := m.styles.Title.Render(m.floors[m.currentFloor].worldMap[m.playerMapY][m.playerMapX].getRoomName())
+	return lipgloss.JoinVertical(lipgloss.Center, title, "", strings.Join(rows, "\n"))
+}
+
+func (m model) renderTile(t tile) string {
+	switch t {
+	case tileWall:
+		return m.styles.Faint.Render("#")
+	case tileDoor:
+		return "+"
+	case tileLockedDoor:
+		return m.styles.Locked.Render("■")
+	case tileTable:
+		return m.styles.Faint.Render("π")
+	case tileBarrel:
+		return m.styles.Faint.Render("o")
+	case tileChest:
+		return m.styles.RoomSpecial.Render("▣")
+	case tileStairsUp:
+		return m.styles.RoomSpecial.Render("▲")
+	case tileStairsDown:
+		return m.styles.RoomSpecial.Render("▼")
+	case tileTrap:
+		return m.styles.Locked.Render("^")
+	case tileMerchant:
+		return m.styles.RoomSpecial.Render("$")
+	case tileBoss:
+		return m.styles.RoomBoss.Render("B")
+	case tileTeleporter:
+		return m.styles.RoomSpecial.Render("◊")
+	case tilePit:
+		return m.styles.RoomSpecial.Render("●")
+	}
+	return m.styles.Faint.Render(".")
+}
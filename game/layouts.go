@@ -0,0 +1,492 @@
+package game
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// layoutsDir holds the hand-made floors. Each .txt file replaces the
+// generated floor of its depth.
+const layoutsDir = "data/layouts"
+
+// Layout is a hand-made floor. Map is drawn like the floor map: rooms sit on
+// even rows and columns and the characters between them are the passages,
+// '-' and '|' for open ones and '#' for locked doors.
+//
+// Room symbols are the ones of the map ('.' for an empty room and '@' for
+// the start of floor 0); other symbols must be defined in Legend.
+type Layout struct {
+	Name      string
+	Depth     int
+	Theme     string
+	Wanderers int
+	Map       [][]rune
+	Legend    map[rune]LegendEntry
+}
+
+// LegendEntry gives a map symbol a room kind and its arguments, for example
+// enemy groups ("enemy goblin goblin") or loot tables ("treasure rare").
+type LegendEntry struct {
+	Kind string
+	Args []string
+}
+
+// legendKinds are the kinds a legend entry can have, with how many
+// arguments they take (-1 for one or more).
+var legendKinds = map[string]int{
+	"empty":      0,
+	"enemy":      -1,
+	"treasure":   1,
+	"shop":       1,
+	"trap":       1,
+	"key":        0,
+	"boss":       1,
+	"teleporter": 0,
+	"pit":        1,
+	"secret":     1,
+	"rune":       0,
+	"switch":     0,
+}
+
+// argsFit reports whether a legend kind takes n arguments.
+func argsFit(kind string, n int) bool {
+	args, ok := legendKinds[kind]
+	return ok && (args == n || (args < 0 && n > 0))
+}
+
+// layoutSymbols are the room symbols that need no legend entry.
+var layoutSymbols = map[rune]roomType{
+	'.': Empty,
+	'@': Empty,
+	'E': Enemy,
+	'T': Tresure,
+	'$': Shop,
+	'^': Trap,
+	'B': Boss,
+	'▲': StairsUp,
+	'▼': StairsDown,
+}
+
+// maxLayoutGroup matches the enemy slots of a room interior.
+const maxLayoutGroup = 3
+
+// loadLayouts reads every layout in dir and checks that it builds a valid
+// floor for its depth. A missing dir just means there are no layouts.
+func loadLayouts(dir string) (map[int]*Layout, error) {
+	layouts := make(map[int]*Layout)
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		l, err := parseLayout(file.Name(), bufio.NewScanner(file))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := layouts[l.Depth]; ok {
+			return nil, fmt.Errorf("%s: depth %d already has layout %q", path, l.Depth, other.Name)
+		}
+		if err := l.check(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		layouts[l.Depth] = l
+	}
+	return layouts, nil
+}
+
+// parseLayout reads a layout: "key value" header lines, then a "map"
+// section and a "legend" section. Lines starting with '#' outside the map
+// are comments.
+func parseLayout(name string, scanner *bufio.Scanner) (*Layout, error) {
+	l := &Layout{Name: strings.TrimSuffix(filepath.Base(name), ".txt"), Legend: map[rune]LegendEntry{}}
+	section := ""
+	hasDepth := false
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("%s:%d: %s", name, line, fmt.Sprintf(format, args...))
+		}
+
+		if trimmed == "map" || trimmed == "legend" {
+			section = trimmed
+			continue
+		}
+		if section == "map" {
+			l.Map = append(l.Map, []rune(text))
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		if section == "legend" {
+			symbol := []rune(fields[0])
+			if len(symbol) != 1 || len(fields) < 2 {
+				return nil, fail("legend lines are a symbol, a kind and its arguments")
+			}
+			if _, ok := layoutSymbols[symbol[0]]; ok || strings.ContainsRune("-|# ", symbol[0]) {
+				return nil, fail("%q is a reserved symbol", symbol[0])
+			}
+			entry := LegendEntry{Kind: fields[1], Args: fields[2:]}
+			if _, ok := legendKinds[entry.Kind]; !ok {
+				return nil, fail("unknown kind %q", entry.Kind)
+			}
+			if !argsFit(entry.Kind, len(entry.Args)) {
+				return nil, fail("wrong number of arguments for %s", entry.Kind)
+			}
+			l.Legend[symbol[0]] = entry
+			continue
+		}
+
+		if len(fields) != 2 {
+			return nil, fail("header lines are a key and a value")
+		}
+		switch fields[0] {
+		case "name":
+			l.Name = fields[1]
+		case "theme":
+			l.Theme = fields[1]
+		case "depth", "wanderers":
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 0 {
+				return nil, fail("%s must be a non-negative number", fields[0])
+			}
+			if fields[0] == "depth" {
+				l.Depth, hasDepth = n, true
+			} else {
+				l.Wanderers = n
+			}
+		default:
+			return nil, fail("unknown key %q", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for len(l.Map) > 0 && strings.TrimSpace(string(l.Map[len(l.Map)-1])) == "" {
+		l.Map = l.Map[:len(l.Map)-1]
+	}
+	if !hasDepth {
+		return nil, fmt.Errorf("%s: missing depth", name)
+	}
+	if len(l.Map) == 0 {
+		return nil, fmt.Errorf("%s: missing map", name)
+	}
+	return l, nil
+}
+
+// check builds the layout once and runs validateFloor on it.
+func (l *Layout) check() error {
+	if _, ok := ThemeTemplates[l.Theme]; l.Theme != "" && !ok {
+		return fmt.Errorf("unknown theme %q", l.Theme)
+	}
+	f, start, err := l.build(0)
+	if err != nil {
+		return err
+	}
+	return validateFloor(f, start, l.Depth)
+}
+
+// newLayoutFloor builds the floor of l for a run. Layouts were checked when
+// they were loaded, so this only fails if the data changed since.
+func newLayoutFloor(l *Layout, seed int64) (*floor, image.Point, error) {
+	f, start, err := l.build(seed)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	f.theme = l.Theme
+	if f.theme == "" {
+		f.theme = themeForFloor(seed, l.Depth)
+	}
+	rng := rand.New(rand.NewSource(floorSeed(seed, -1)))
+	placeWanderers(rng, f, start, l.Wanderers, themeEnemies(f.theme))
+	return f, start, nil
+}
+
+// hasBoss reports whether the layout has a boss room.
+func (l *Layout) hasBoss() bool {
+	for _, row := range l.Map {
+		for _, symbol := range row {
+			if symbol == 'B' || l.Legend[symbol].Kind == "boss" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// size is the room grid the map is drawn on.
+func (l *Layout) size() (int, int) {
+	width := 0
+	for _, row := range l.Map {
+		width = max(width, len(row))
+	}
+	return (width + 1) / 2, (len(l.Map) + 1) / 2
+}
+
+func (l *Layout) at(x, y int) rune {
+	if y < 0 || y >= len(l.Map) || x < 0 || x >= len(l.Map[y]) {
+		return ' '
+	}
+	return l.Map[y][x]
+}
+
+// build makes a fresh floor from the layout. The seed only decides what the
+// layout leaves open: traps and bosses of the plain symbols, shop stock and
+// the enemies of 'E' rooms.
+func (l *Layout) build(seed int64) (*floor, image.Point, error) {
+	rng := rand.New(rand.NewSource(seed))
+	width, height := l.size()
+	worldMap := newWorldMap(width, height)
+	f := &floor{worldMap: worldMap, seed: seed}
+
+	var start, downStairs []image.Point
+	teleporters := map[rune][]image.Point{}
+	switches, runes := 0, 0
+	for y := range height {
+		for x := range width {
+			symbol := l.at(2*x, 2*y)
+			if symbol == ' ' {
+				continue
+			}
+			r := &room{}
+			worldMap[y][x] = r
+			coord := image.Point{X: x, Y: y}
+
+			t, plain := layoutSymbols[symbol]
+			entry, defined := l.Legend[symbol]
+			switch {
+			case plain:
+				r.Type = t
+			case defined:
+				if err := l.applyLegend(f, r, entry); err != nil {
+					return nil, image.Point{}, fmt.Errorf("room %q at %v: %w", symbol, coord, err)
+				}
+			default:
+				return nil, image.Point{}, fmt.Errorf("room %q at %v is not in the legend", symbol, coord)
+			}
+
+			switch {
+			case symbol == '@':
+				start = append(start, coord)
+			case r.Type == StairsDown:
+				downStairs = append(downStairs, coord)
+			case r.Type == Teleporter:
+				teleporters[symbol] = append(teleporters[symbol], coord)
+			case r.Type == Tresure && r.LootTable == "":
+				r.LootTable = "common"
+			case r.Type == Trap && r.Trap == "":
+				if ids := trapIDs(); len(ids) > 0 {
+					r.Trap = ids[rng.Intn(len(ids))]
+				}
+			case r.Type == Boss && f.boss == "":
+				if f.boss = bossForDepth(rng, l.Depth); f.boss == "" {
+					return nil, image.Point{}, fmt.Errorf("no boss is allowed at depth %d", l.Depth)
+				}
+			}
+			if r.Type == Shop {
+				if r.LootTable == "" {
+					r.LootTable = "shop"
+				}
+				r.restock(roomSeed(seed, x, y))
+			}
+			if r.Switch {
+				switches++
+			}
+			if r.HasRune {
+				runes++
+			}
+		}
+	}
+
+	for y, row := range l.Map {
+		for x, symbol := range row {
+			switch {
+			case symbol == ' ' || (x%2 == 0 && y%2 == 0):
+				continue
+			case x%2 == 1 && y%2 == 1:
+				return nil, image.Point{}, fmt.Errorf("unexpected %q at line %d, column %d of the map", symbol, y+1, x+1)
+			}
+			// Passages sit between two rooms on the same row or column.
+			a, b := image.Point{X: (x - 1) / 2, Y: y / 2}, image.Point{X: (x + 1) / 2, Y: y / 2}
+			if y%2 == 1 {
+				a, b = image.Point{X: x / 2, Y: (y - 1) / 2}, image.Point{X: x / 2, Y: (y + 1) / 2}
+			}
+			if symbol != '-' && symbol != '|' && symbol != '#' {
+				return nil, image.Point{}, fmt.Errorf("unexpected %q between rooms %v and %v", symbol, a, b)
+			}
+			if !inBounds(worldMap, b.X, b.Y) || worldMap[a.Y][a.X] == nil || worldMap[b.Y][b.X] == nil {
+				return nil, image.Point{}, fmt.Errorf("passage at line %d, column %d does not join two rooms", y+1, x+1)
+			}
+			connectRooms(worldMap, a, b)
+			if symbol == '#' {
+				exit := worldMap[a.Y][a.X].Exits[East]
+				if y%2 == 1 {
+					exit = worldMap[a.Y][a.X].Exits[South]
+				}
+				exit.Locked = true
+			}
+		}
+	}
+
+	for symbol, pair := range teleporters {
+		if len(pair) != 2 {
+			return nil, image.Point{}, fmt.Errorf("teleporter %q needs exactly two rooms, has %d", symbol, len(pair))
+		}
+		worldMap[pair[0].Y][pair[0].X].Link = pair[1]
+		worldMap[pair[1].Y][pair[1].X].Link = pair[0]
+	}
+
+	switch {
+	case switches > 0 && runes > 0:
+		return nil, image.Point{}, errors.New("a floor has either a rune or switches, not both")
+	case switches > 0:
+		f.objective = &objective{kind: objectiveSwitches, count: switches}
+	case runes > 0:
+		f.objective = &objective{kind: objectiveRune}
+	}
+
+	start = append(start, downStairs...)
+	if len(start) != 1 {
+		return nil, image.Point{}, fmt.Errorf("needs one start ('@' on floor 0, the down stairs above it), has %d", len(start))
+	}
+	return f, start[0], nil
+}
+
+// applyLegend sets up r from its legend entry.
+func (l *Layout) applyLegend(f *floor, r *room, entry LegendEntry) error {
+	switch entry.Kind {
+	case "enemy":
+		if len(entry.Args) > maxLayoutGroup {
+			return fmt.Errorf("enemy groups have at most %d enemies", maxLayoutGroup)
+		}
+		for _, id := range entry.Args {
+			if _, ok := EnemyTemplates[id]; !ok {
+				return fmt.Errorf("unknown enemy %q", id)
+			}
+		}
+		r.Type, r.Enemies = Enemy, entry.Args
+	case "treasure", "shop", "secret":
+		if _, ok := LootTemplates[entry.Args[0]]; !ok {
+			return fmt.Errorf("unknown loot table %q", entry.Args[0])
+		}
+		r.Type, r.LootTable = Tresure, entry.Args[0]
+		if entry.Kind == "shop" {
+			r.Type = Shop
+		}
+		r.Secret, r.Hidden = entry.Kind == "secret", entry.Kind == "secret"
+	case "trap":
+		if _, ok := TrapTemplates[entry.Args[0]]; !ok {
+			return fmt.Errorf("unknown trap %q", entry.Args[0])
+		}
+		r.Type, r.Trap = Trap, entry.Args[0]
+	case "boss":
+		if _, ok := BossTemplates[entry.Args[0]]; !ok {
+			return fmt.Errorf("unknown boss %q", entry.Args[0])
+		}
+		if f.boss != "" && f.boss != entry.Args[0] {
+			return errors.New("a floor has a single boss")
+		}
+		r.Type, f.boss = Boss, entry.Args[0]
+	case "pit":
+		drop, err := strconv.Atoi(entry.Args[0])
+		if err != nil || drop < 1 {
+			return fmt.Errorf("pits drop at least one floor")
+		}
+		r.Type, r.Drop = Pit, drop
+	case "teleporter":
+		r.Type = Teleporter
+	case "key":
+		r.HasKey = true
+	case "rune":
+		r.HasRune = true
+	case "switch":
+		r.Switch = true
+	}
+	return nil
+}
+
+// format writes the layout in the format parseLayout reads. Legend entries
+// no room uses are left out.
+func (l *Layout) format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "depth %d\n", l.Depth)
+	if l.Theme != "" {
+		fmt.Fprintf(&b, "theme %s\n", l.Theme)
+	}
+	if l.Wanderers > 0 {
+		fmt.Fprintf(&b, "wanderers %d\n", l.Wanderers)
+	}
+
+	b.WriteString("\nmap\n")
+	used := map[rune]bool{}
+	for _, row := range l.Map {
+		for _, symbol := range row {
+			used[symbol] = true
+		}
+		b.WriteString(strings.TrimRight(string(row), " ") + "\n")
+	}
+
+	var symbols []rune
+	for symbol := range l.Legend {
+		if used[symbol] {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return b.String()
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	b.WriteString("\nlegend\n")
+	for _, symbol := range symbols {
+		entry := l.Legend[symbol]
+		fmt.Fprintf(&b, "%c %s\n", symbol, strings.TrimSpace(entry.Kind+" "+strings.Join(entry.Args, " ")))
+	}
+	return b.String()
+}
+
+// saveLayout writes l to dir as <name>.txt. It refuses to take a depth that
+// another layout already has, since the server would not start.
+func saveLayout(dir string, l *Layout) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, l.Name+".txt")
+	for _, other := range paths {
+		if other == path {
+			continue
+		}
+		file, err := os.Open(other)
+		if err != nil {
+			return err
+		}
+		parsed, err := parseLayout(other, bufio.NewScanner(file))
+		file.Close()
+		if err == nil && parsed.Depth == l.Depth {
+			return fmt.Errorf("depth %d already has layout %q", l.Depth, parsed.Name)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(l.format()), 0o644)
+}
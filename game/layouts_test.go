@@ -0,0 +1,85 @@
+package game
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exampleLayoutsDir holds sample layouts. They are not loaded by the game,
+// since loadLayouts does not look into subdirectories of layoutsDir.
+const exampleLayoutsDir = layoutsDir + "/examples"
+
+func TestExampleLayouts(t *testing.T) {
+	tutorial := tutorialLayout(t)
+	if tutorial.Name != "tutorial" {
+		t.Fatalf("the layout for floor 0 is %q, want the tutorial", tutorial.Name)
+	}
+	for seed := range int64(50) {
+		f, start, err := newLayoutFloor(tutorial, seed)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if err := validateFloor(f, start, tutorial.Depth); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
+
+// tutorialLayout loads the sample tutorial layout.
+func tutorialLayout(t *testing.T) *Layout {
+	t.Helper()
+	layouts, err := loadLayouts(exampleLayoutsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, ok := layouts[0]
+	if !ok {
+		t.Fatalf("%s has no layout for floor 0", exampleLayoutsDir)
+	}
+	return l
+}
+
+func TestLayoutFormatRoundTrip(t *testing.T) {
+	l := tutorialLayout(t)
+	l.Wanderers = 2
+
+	parsed, err := parseLayout(l.Name+".txt", bufio.NewScanner(strings.NewReader(l.format())))
+	if err != nil {
+		t.Fatalf("parsing the formatted layout: %v\n%s", err, l.format())
+	}
+	if !reflect.DeepEqual(parsed, l) {
+		t.Errorf("round trip changed the layout:\ngot  %+v\nwant %+v", parsed, l)
+	}
+}
+
+func TestSaveLayout(t *testing.T) {
+	dir := t.TempDir()
+	l := tutorialLayout(t)
+	if err := saveLayout(dir, l); err != nil {
+		t.Fatal(err)
+	}
+	// Saving the same layout again overwrites it.
+	if err := saveLayout(dir, l); err != nil {
+		t.Fatalf("saving %s again: %v", l.Name, err)
+	}
+
+	saved, err := loadLayouts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved[l.Depth], l) {
+		t.Errorf("loaded %+v, want %+v", saved[l.Depth], l)
+	}
+
+	other := *l
+	other.Name = "other"
+	if err := saveLayout(dir, &other); err == nil {
+		t.Errorf("saved %s at depth %d, which %s already has", other.Name, other.Depth, l.Name)
+	}
+	other.Depth = 1
+	if err := saveLayout(dir, &other); err != nil {
+		t.Errorf("saving %s at a free depth: %v", other.Name, err)
+	}
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+)
+
+type roomEdge struct {
+	a, b image.Point
+	p    *passage
+}
+
+// placeLocks locks up to count passages and hides one key for each of them.
+// Only passages that actually cut the floor are locked (a teleporter pair
+// leading around a door counts as a way through), and every key stays in a
+// room the player can reach with all doors still locked, so keys are always
+// found before the doors they open, whichever door is opened first.
+func placeLocks(rng *rand.Rand, worldMap [][]*room, start image.Point, count int) {
+	for range count {
+		reachable := reachableFrom(worldMap, start, false)
+
+		type candidate struct {
+			edge     roomEdge
+			keyRooms []image.Point
+		}
+		var candidates []candidate
+
+		for _, edge := range floorEdges(worldMap) {
+			if edge.p.Locked || edge.a == start || edge.b == start || !reachable[edge.a] || !reachable[edge.b] {
+				continue
+			}
+
+			edge.p.Locked = true
+			before := reachableFrom(worldMap, start, false)
+			edge.p.Locked = false
+
+			if len(before) == len(reachable) {
+				continue
+			}
+
+			var keyRooms []image.Point
+			hidesKey := false
+			for _, coord := range roomCoords(worldMap) {
+				r := worldMap[coord.Y][coord.X]
+				if r.HasKey && !before[coord] {
+					hidesKey = true
+					break
+				}
+				if before[coord] && coord != start && !r.HasKey && (r.Type == Empty || r.Type == Tresure) {
+					keyRooms = append(keyRooms, coord)
+				}
+			}
+			if !hidesKey && len(keyRooms) > 0 {
+				candidates = append(candidates, candidate{edge: edge, keyRooms: keyRooms})
+			}
+		}
+
+		if len(candidates) == 0 {
+			return
+		}
+
+		chosen := candidates[rng.Intn(len(candidates))]
+		chosen.edge.p.Locked = true
+		keyRoom := chosen.keyRooms[rng.Intn(len(chosen.keyRooms))]
+		worldMap[keyRoom.Y][keyRoom.X].HasKey = true
+	}
+}
+
+// floorEdges lists every passage of the floor once, in a stable order.
+func floorEdges(worldMap [][]*room) []roomEdge {
+	var edges []roomEdge
+	for _, coord := range roomCoords(worldMap) {
+		for _, dir := range []direction{South, East} {
+			if p := worldMap[coord.Y][coord.X].Exits[dir]; p != nil {
+				edges = append(edges, roomEdge{a: coord, b: coord.Add(cardinalDirections[dir]), p: p})
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,32 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestPlaceLocksAroundTeleporters(t *testing.T) {
+	// start - a - b - c in a row, with a teleporter pair linking a and c.
+	coords := []image.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}}
+	for seed := range int64(20) {
+		worldMap := newWorldMap(4, 1)
+		for i, coord := range coords {
+			worldMap[coord.Y][coord.X] = &room{}
+			if i > 0 {
+				connectRooms(worldMap, coords[i-1], coord)
+			}
+		}
+		a, c := coords[1], coords[3]
+		worldMap[a.Y][a.X].Type, worldMap[a.Y][a.X].Link = Teleporter, c
+		worldMap[c.Y][c.X].Type, worldMap[c.Y][c.X].Link = Teleporter, a
+
+		// No door cuts the floor: the teleporters lead around any of them.
+		placeLocks(rand.New(rand.NewSource(seed)), worldMap, coords[0], 1)
+		for _, edge := range floorEdges(worldMap) {
+			if edge.p.Locked {
+				t.Fatalf("seed %d: locked %v-%v, which the teleporters go around", seed, edge.a, edge.b)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// LootTable describes what a chest can hold. Each roll picks one entry of
+// Items by weight.
+type LootTable struct {
+	Rolls   int         `json:"rolls"`
+	GoldMin int         `json:"goldMin"`
+	GoldMax int         `json:"goldMax"`
+	Items   []LootEntry `json:"items"`
+}
+
+type LootEntry struct {
+	Item   string `json:"item"`
+	Weight int    `json:"weight"`
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+}
+
+func (t LootTable) roll(rng *rand.Rand) (int, map[string]int) {
+	gold := t.GoldMin
+	if t.GoldMax > t.GoldMin {
+		gold += rng.Intn(t.GoldMax - t.GoldMin + 1)
+	}
+
+	totalWeight := 0
+	for _, entry := range t.Items {
+		totalWeight += entry.Weight
+	}
+
+	items := make(map[string]int)
+	for range t.Rolls {
+		if totalWeight <= 0 {
+			break
+		}
+		pick := rng.Intn(totalWeight)
+		for _, entry := range t.Items {
+			if pick < entry.Weight {
+				count := entry.Min
+				if entry.Max > entry.Min {
+					count += rng.Intn(entry.Max - entry.Min + 1)
+				}
+				items[entry.Item] += count
+				break
+			}
+			pick -= entry.Weight
+		}
+	}
+	return gold, items
+}
+
+// openChest gives the player the contents of the chest in the current room
+// and returns a message describing them. The contents depend only on the
+// floor seed and the room, so seeded runs find the same loot.
+func (m *model) openChest(r *room) string {
+	table, ok := LootTemplates[r.LootTable]
+	if !ok {
+		table = LootTemplates["common"]
+	}
+
+	rng := rand.New(rand.NewSource(roomSeed(m.floors[m.currentFloor].seed, m.playerMapX, m.playerMapY)))
+	gold, items := table.roll(rng)
+	m.player.gold += gold
+
+	found := []string{fmt.Sprintf("%d gold", gold)}
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		m.player.inventory[id] += items[id]
+		found = append(found, fmt.Sprintf("%s x%d", ItemTemplates[id].Name, items[id]))
+	}
+
+	return "You open the chest: " + strings.Join(found, ", ")
+}
@@ -2,55 +2,98 @@ package game
 
 import (
 	"image"
+	"log"
 	"math/rand"
-	"time"
 )
 
-func generateMap(width, height, maxRooms, floorNum int) (*floor, int, int) {
-	rand.New(rand.NewSource(time.Now().UnixNano()))
+// floorSeed derives the seed of a floor from the run seed, so the same run
+// seed always produces the same sequence of floors.
+func floorSeed(runSeed int64, floorNum int) int64 {
+	z := uint64(runSeed) + uint64(floorNum+1)*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return int64(z ^ (z >> 31))
+}
 
-	worldMap := make([][]*room, height)
-	for i := range worldMap {
-		worldMap[i] = make([]*room, width)
+// newFloor generates floor floorNum of the run using the progression table.
+// Layouts that break validateFloor are generated again from a derived seed,
+// and after maxFloorAttempts the floor falls back to fallbackFloor. Depths
+// with a hand-made layout are built from it instead.
+func newFloor(runSeed int64, floorNum int) (*floor, int, int) {
+	seed := floorSeed(runSeed, floorNum)
+	if l, ok := LayoutTemplates[floorNum]; ok {
+		f, start, err := newLayoutFloor(l, seed)
+		if err == nil {
+			return f, start.X, start.Y
+		}
+		log.Printf("Layout %s for floor %d failed, generating it instead: %v", l.Name, floorNum, err)
 	}
+	params := floorParamsFor(floorNum)
 
-	var allRoomCoords []image.Point
-	startX, startY := width/2, height/2
-	currentX, currentY := startX, startY
-	roomsCreated := 0
+	theme := themeForFloor(seed, floorNum)
+	if t := ThemeTemplates[theme]; len(t.Generators) > 0 {
+		params.Generators = t.Generators
+	}
 
-	for roomsCreated < maxRooms {
-		if worldMap[currentY][currentX] == nil {
-			worldMap[currentY][currentX] = &room{}
-			allRoomCoords = append(allRoomCoords, image.Point{X: currentX, Y: currentY})
-			roomsCreated++
+	var f *floor
+	var startX, startY int
+	var err error
+	for attempt := range maxFloorAttempts {
+		f, startX, startY = generateMap(generatorForFloor(seed, params), seed, params, floorNum)
+		placeObjective(rand.New(rand.NewSource(floorSeed(seed, -2))), f, image.Point{X: startX, Y: startY}, floorNum)
+		if err = validateFloor(f, image.Point{X: startX, Y: startY}, floorNum); err == nil {
+			break
 		}
+		seed = floorSeed(seed, attempt)
+	}
+	if err != nil {
+		log.Printf("Run seed %d: no valid layout after %d attempts, using the fallback floor: %v", runSeed, maxFloorAttempts, err)
+		f, startX, startY = fallbackFloor(seed, floorNum)
+	}
+	f.theme = theme
 
-		dx, dy := 0, 0
-		switch rand.Intn(4) {
-		case 0:
-			dy = -1
-		case 1:
-			dy = 1
-		case 2:
-			dx = -1
-		case 3:
-			dx = 1
-		}
+	rng := rand.New(rand.NewSource(floorSeed(seed, -1)))
+	placeWanderers(rng, f, image.Point{X: startX, Y: startY}, params.Wanderers, themeEnemies(theme))
+	return f, startX, startY
+}
 
-		if currentX+dx >= 0 && currentX+dx < width && currentY+dy >= 0 && currentY+dy < height {
-			currentX += dx
-			currentY += dy
-		}
+// fallbackFloor is a short corridor from the start room to the StairsUp. It
+// always passes validateFloor, so newFloor uses it when every generated
+// layout failed.
+func fallbackFloor(seed int64, floorNum int) (*floor, int, int) {
+	worldMap := newWorldMap(3, 1)
+	for x := range 3 {
+		worldMap[0][x] = &room{}
+	}
+	if floorNum > 0 {
+		worldMap[0][0].Type = StairsDown
 	}
+	worldMap[0][2].Type = StairsUp
+	connectRooms(worldMap, image.Point{X: 0, Y: 0}, image.Point{X: 1, Y: 0})
+	connectRooms(worldMap, image.Point{X: 1, Y: 0}, image.Point{X: 2, Y: 0})
+	return &floor{worldMap: worldMap, seed: seed}, 0, 0
+}
+
+// roomSeed derives a seed for things that happen in a single room.
+func roomSeed(floorSeed int64, x, y int) int64 {
+	return floorSeed ^ int64(x+1)*0x2545F4914F6CDD1D ^ int64(y+1)*0x5851F42D4C957F2D
+}
+
+func generateMap(gen mapGenerator, seed int64, params FloorParams, floorNum int) (*floor, int, int) {
+	rng := rand.New(rand.NewSource(seed))
+
+	maxRooms := min(params.Rooms, params.Width*params.Height)
+	worldMap, start := gen.carve(rng, params.Width, params.Height, maxRooms)
+	allRoomCoords := roomCoords(worldMap)
+	startX, startY := start.X, start.Y
 
 	totalRooms := len(allRoomCoords)
 
-	rand.Shuffle(len(allRoomCoords), func(i, j int) {
+	rng.Shuffle(len(allRoomCoords), func(i, j int) {
 		allRoomCoords[i], allRoomCoords[j] = allRoomCoords[j], allRoomCoords[i]
 	})
 
-	enemyRatio := 0.4 + rand.Float64()*0.2
+	enemyRatio := params.EnemyRatioMin + rng.Float64()*(params.EnemyRatioMax-params.EnemyRatioMin)
 	numEnemies := int(float64(totalRooms) * enemyRatio)
 	assignedCount := 0
 	for i := range numEnemies {
@@ -59,90 +102,145 @@ func generateMap(width, height, maxRooms, floorNum int) (*floor, int, int) {
 		assignedCount++
 	}
 
-	numTreasures := 1 + rand.Intn(3)
+	numTreasures := params.TreasuresMin + rng.Intn(params.TreasuresMax-params.TreasuresMin+1)
 	for i := range numTreasures {
 		if assignedCount+i < totalRooms {
 			coord := allRoomCoords[assignedCount+i]
 			worldMap[coord.Y][coord.X].Type = Tresure
+			worldMap[coord.Y][coord.X].LootTable = "common"
 		}
 	}
 	assignedCount += numTreasures
 
-	if rand.Intn(2) == 0 {
+	if ids := trapIDs(); len(ids) > 0 {
+		for i := range params.Traps {
+			if assignedCount+i < totalRooms {
+				coord := allRoomCoords[assignedCount+i]
+				worldMap[coord.Y][coord.X].Type = Trap
+				worldMap[coord.Y][coord.X].Trap = ids[rng.Intn(len(ids))]
+			}
+		}
+		assignedCount += params.Traps
+	}
+
+	worldMap[startY][startX].Type = Empty
+	worldMap[startY][startX].Trap = ""
+	worldMap[startY][startX].LootTable = ""
+
+	if rng.Float64() < params.ShopChance {
 		var potentialShopSpots []image.Point
 		for _, coord := range allRoomCoords {
 			room := worldMap[coord.Y][coord.X]
-			if room.Type == 0 && isAdjacentToEnemy(coord.X, coord.Y, worldMap) {
+			if room.Type == 0 && coord != start && isAdjacentToEnemy(coord.X, coord.Y, worldMap) {
 				potentialShopSpots = append(potentialShopSpots, coord)
 			}
 		}
 
 		if len(potentialShopSpots) > 0 {
-			shopCoord := potentialShopSpots[rand.Intn(len(potentialShopSpots))]
-			worldMap[shopCoord.Y][shopCoord.X].Type = Shop
+			shopCoord := potentialShopSpots[rng.Intn(len(potentialShopSpots))]
+			shop := worldMap[shopCoord.Y][shopCoord.X]
+			shop.Type = Shop
+			shop.LootTable = "shop"
+			shop.restock(roomSeed(seed, shopCoord.X, shopCoord.Y))
 		}
 	}
 
-	var startCoords image.Point
+	startCoords := image.Point{X: startX, Y: startY}
 	if floorNum > 0 {
-		var potentialDownStairsSpots []image.Point
-		for _, coord := range allRoomCoords {
-			if worldMap[coord.Y][coord.X].Type == 0 {
-				potentialDownStairsSpots = append(potentialDownStairsSpots, coord)
-			}
-		}
-		if len(potentialDownStairsSpots) > 0 {
-			downStairsCoord := potentialDownStairsSpots[rand.Intn(len(potentialDownStairsSpots))]
+		anyRoom := func(image.Point) bool { return true }
+		if downStairsCoord, ok := pickStairsSpot(rng, worldMap, allRoomCoords, anyRoom); ok {
 			worldMap[downStairsCoord.Y][downStairsCoord.X].Type = StairsDown
 			startCoords = downStairsCoord
-		} else {
-			startCoords = image.Point{X: startX, Y: startY}
 		}
-	} else {
-		startCoords = image.Point{X: startX, Y: startY}
 	}
 
-	var potentialUpStairsSpots []image.Point
-	for _, coord := range allRoomCoords {
-		if worldMap[coord.Y][coord.X].Type == 0 {
+	upStairsCoord, ok := pickStairsSpot(rng, worldMap, allRoomCoords, func(coord image.Point) bool {
+		return abs(coord.X-startX)+abs(coord.Y-startY) > 1
+	})
+	if !ok {
+		// Tiny floors may have no room far enough from the start.
+		upStairsCoord, ok = pickStairsSpot(rng, worldMap, allRoomCoords, func(coord image.Point) bool {
+			return coord != startCoords
+		})
+	}
+	var boss string
+	if ok {
+		worldMap[upStairsCoord.Y][upStairsCoord.X].Type = StairsUp
 
-			manhattanDistance := abs(coord.X-startX) + abs(coord.Y-startY)
-			if manhattanDistance > 1 {
-				potentialUpStairsSpots = append(potentialUpStairsSpots, coord)
+		if isBossFloor(params, floorNum) {
+			boss = bossForDepth(rng, floorNum)
+			if boss != "" && !placeBossRoom(rng, worldMap, startCoords, upStairsCoord) {
+				boss = ""
 			}
 		}
 	}
-	if len(potentialUpStairsSpots) > 0 {
-		upStairsCoord := potentialUpStairsSpots[rand.Intn(len(potentialUpStairsSpots))]
-		worldMap[upStairsCoord.Y][upStairsCoord.X].Type = StairsUp
-	}
 
-	for _, coord := range allRoomCoords {
-		if worldMap[coord.Y][coord.X].Type == 0 {
-			worldMap[coord.Y][coord.X].Type = Empty
-		}
+	if params.Teleporters > 0 {
+		placeTeleporters(rng, worldMap, startCoords, params.Teleporters)
+	}
+	if params.Pits > 0 && !isBossFloor(params, floorNum) {
+		placePits(rng, worldMap, startCoords, params.Pits, params.PitDrop)
 	}
 
-	worldMap[startY][startX].Type = Empty
+	placeLocks(rng, worldMap, startCoords, params.LockedDoors)
+	placeSecretRooms(rng, worldMap, startCoords, params.SecretRooms)
 
 	newFloor := &floor{
 		worldMap: worldMap,
+		seed:     seed,
+		boss:     boss,
 	}
 
 	return newFloor, startCoords.X, startCoords.Y
 }
 
-func isAdjacentToEnemy(x, y int, worldMap [][]*room) bool {
-	directions := []image.Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
-	width, height := len(worldMap[0]), len(worldMap)
-
-	for _, dir := range directions {
-		checkX, checkY := x+dir.X, y+dir.Y
-		if checkX >= 0 && checkX < width && checkY >= 0 && checkY < height {
-			if neightbor := worldMap[checkY][checkX]; neightbor != nil && neightbor.Type == Enemy {
-				return true
+// pickStairsSpot picks a free room that passes allowed. When there is none
+// it takes over an enemy, treasure or trap room instead, so the stairs are
+// never left out of a crowded floor.
+func pickStairsSpot(rng *rand.Rand, worldMap [][]*room, coords []image.Point, allowed func(image.Point) bool) (image.Point, bool) {
+	for _, reuse := range []bool{false, true} {
+		var spots []image.Point
+		for _, coord := range coords {
+			r := worldMap[coord.Y][coord.X]
+			free := r.Type == Empty
+			if reuse {
+				free = r.Type == Enemy || r.Type == Tresure || r.Type == Trap
+			}
+			if free && allowed(coord) {
+				spots = append(spots, coord)
 			}
 		}
+		if len(spots) > 0 {
+			spot := spots[rng.Intn(len(spots))]
+			worldMap[spot.Y][spot.X].Trap = ""
+			worldMap[spot.Y][spot.X].LootTable = ""
+			return spot, true
+		}
+	}
+	return image.Point{}, false
+}
+
+func roomCoords(worldMap [][]*room) []image.Point {
+	var coords []image.Point
+	for y, row := range worldMap {
+		for x, room := range row {
+			if room != nil {
+				coords = append(coords, image.Point{X: x, Y: y})
+			}
+		}
+	}
+	return coords
+}
+
+func isAdjacentToEnemy(x, y int, worldMap [][]*room) bool {
+	for dir, exit := range worldMap[y][x].Exits {
+		if exit == nil {
+			continue
+		}
+		checkX, checkY := x+cardinalDirections[dir].X, y+cardinalDirections[dir].Y
+		if neightbor := worldMap[checkY][checkX]; neightbor != nil && neightbor.Type == Enemy {
+			return true
+		}
 	}
 
 	return false
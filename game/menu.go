@@ -1,6 +1,8 @@
 package game
 
 import (
+	"math/rand"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -23,7 +25,12 @@ func (m model) updateMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.menuCursor == 0 {
 				m.state = StateGame
 
-				firstFloor, startX, startY := generateMap(9, 9, 15, 0)
+				m.runSeed = rand.Int63()
+				if m.options.HasSeed {
+					m.runSeed = m.options.Seed
+				}
+
+				firstFloor, startX, startY := newFloor(m.runSeed, 0)
 				m.floors = []floor{*firstFloor}
 				m.currentFloor = 0
 
@@ -38,10 +45,11 @@ func (m model) updateMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
 						strength: 8,
 						defense:  8,
 					},
-					inventory: make(map[string]int),
+					inventory: map[string]int{"potion": 1},
 				}
 
 				m.floors[m.currentFloor].worldMap[startY][startX].Visited = true
+				m.revealAround()
 			} else {
 				return m, tea.Quit
 			}
@@ -1,16 +1,30 @@
 package game
 
 import (
+	"image"
+
 	"github.com/charmbracelet/bubbles/progress"
 )
 
 type GameState int
 
+// Options configures a new game session.
+type Options struct {
+	StartState GameState
+	// Seed is used for every run when HasSeed is set; otherwise each run
+	// picks a random seed.
+	Seed    int64
+	HasSeed bool
+	// Layout names the hand-made floor StateEditor opens.
+	Layout string
+}
+
 const (
 	StateLoading GameState = iota
 	StateMenu
 	StateGame
 	StateCombat
+	StateEditor
 )
 
 const playerArt = `👤YOU`
@@ -22,6 +36,7 @@ type playerStats struct {
 type playerData struct {
 	stats     playerStats
 	inventory map[string]int
+	gold      int
 }
 
 type roomType int
@@ -33,15 +48,99 @@ const (
 	Shop
 	StairsUp
 	StairsDown
+	Trap
+	Boss
+	Teleporter
+	Pit
+)
+
+// direction indexes room exits. The order matches cardinalDirections.
+type direction int
+
+const (
+	North direction = iota
+	South
+	West
+	East
 )
 
+// opposite relies on North/South and West/East being adjacent values.
+func (d direction) opposite() direction {
+	return d ^ 1
+}
+
+// passage is the connection between two neighboring rooms. Both rooms share
+// the same passage, so unlocking it from either side opens it for both.
+type passage struct {
+	Locked bool
+}
+
 type room struct {
 	Type    roomType
 	Visited bool
+	// Seen rooms are drawn on the map as [?] until they are visited. Rooms
+	// that were neither seen nor visited are not drawn at all.
+	Seen   bool
+	Exits  [4]*passage
+	HasKey bool
+
+	// Secret rooms stay Hidden until the player finds them by searching
+	// a neighboring room.
+	Secret    bool
+	Hidden    bool
+	LootTable string
+
+	Trap         string
+	TrapDetected bool
+
+	// Enemies is the group of an enemy room of a hand-made layout. Other
+	// enemy rooms pick theirs from the floor theme, see roomEnemyIDs.
+	Enemies []string
+
+	// HasRune and Switch hold the floor objective, see objective.
+	HasRune  bool
+	Switch   bool
+	SwitchOn bool
+
+	// Link is the other room of a teleporter pair and Drop the number of
+	// floors a pit falls.
+	Link image.Point
+	Drop int
+
+	// Stock is what a shop has for sale.
+	Stock map[string]int
+
+	// Note and Marker are left by the player from the full map.
+	Note   string
+	Marker string
+
+	// Was is what the room held before the player emptied it, at floor
+	// turn ClearedAt; see RespawnRule. Empty when nothing can come back.
+	Was       roomType
+	ClearedAt int
 }
 
 type floor struct {
 	worldMap [][]*room
+	seed     int64
+	theme    string
+	// turn is the floor clock. It advances with every step and rest, and
+	// catches up with the time spent elsewhere when the player comes back.
+	turn int
+	// leftAt is the run clock when the player last left the floor.
+	leftAt int
+
+	// boss guards the StairsUp room; the stairs stay sealed until it is
+	// defeated. Empty on regular floors.
+	boss         string
+	bossDefeated bool
+
+	// mapped floors show every passage between seen rooms, not only the
+	// passages of visited ones.
+	mapped bool
+
+	wanderers []*wanderer
+	objective *objective
 }
 
 type CombatState struct {
@@ -55,6 +154,11 @@ type CombatState struct {
 	targetCursor          int
 	isEnemyTurnInProgress bool
 	enemyActionProgress   progress.Model
+
+	boss      string
+	showIntro bool
+	// surprise is set until the player's first turn of an ambush.
+	surprise bool
 }
 
 type model struct {
@@ -67,11 +171,37 @@ type model struct {
 
 	menuCursor int
 
+	options      Options
+	runSeed      int64
 	floors       []floor
 	currentFloor int
 	playerMapX   int
 	playerMapY   int
 	player       playerData
 
+	// clock counts the turns of the whole run, on any floor.
+	clock int
+
 	combat *CombatState
+
+	message     string
+	showFullMap bool
+	// mapCursor is the room inspected on the full map. While editingNote
+	// is set, keys type noteDraft instead of moving it.
+	mapCursor   image.Point
+	editingNote bool
+	noteDraft   string
+
+	// travelPath holds the exits left to walk when traveling; autoexploring
+	// picks a new path each time it runs out.
+	travelPath    []direction
+	autoexploring bool
+	travelID      int
+
+	// zoomed shows the inside of the current room; tileX, tileY is the
+	// player's position in it.
+	zoomed       bool
+	tileX, tileY int
+
+	editor *editorState
 }
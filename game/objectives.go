@@ -0,0 +1,200 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"sort"
+)
+
+// Objective kinds. While a floor objective is not complete the StairsUp room
+// stays sealed.
+const (
+	objectiveSlay     = "slay"     // defeat every enemy of the floor
+	objectiveRune     = "rune"     // find the rune hidden in one room
+	objectiveSwitches = "switches" // activate the switches in Count rooms
+)
+
+// ObjectiveTable is an entry of data/objectives.json. Like the floor
+// progression, it applies from its depth until the next entry. Floors get
+// an objective with the given Chance, picked from Objectives by weight.
+type ObjectiveTable struct {
+	Depth      int              `json:"depth"`
+	Chance     float64          `json:"chance"`
+	Objectives []ObjectiveEntry `json:"objectives"`
+}
+
+type ObjectiveEntry struct {
+	Type   string `json:"type"`
+	Weight int    `json:"weight"`
+	Count  int    `json:"count"`
+}
+
+// objective is the goal of a floor. done stays set once it is reached, even
+// if enemies come back later.
+type objective struct {
+	kind  string
+	count int
+	done  bool
+}
+
+func objectiveTableFor(depth int) ObjectiveTable {
+	var table ObjectiveTable
+	for _, entry := range ObjectiveTemplates {
+		if entry.Depth > depth {
+			break
+		}
+		table = entry
+	}
+	return table
+}
+
+func validateObjectives(tables []ObjectiveTable) error {
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Depth < tables[j].Depth
+	})
+	for _, table := range tables {
+		if table.Chance < 0 || table.Chance > 1 {
+			return fmt.Errorf("objectives: depth %d has an invalid chance", table.Depth)
+		}
+		for _, entry := range table.Objectives {
+			switch entry.Type {
+			case objectiveSlay, objectiveRune:
+			case objectiveSwitches:
+				if entry.Count < 1 {
+					return fmt.Errorf("objectives: depth %d needs at least one switch", table.Depth)
+				}
+			default:
+				return fmt.Errorf("objectives: depth %d has unknown objective %q", table.Depth, entry.Type)
+			}
+			if entry.Weight <= 0 {
+				return fmt.Errorf("objectives: depth %d has a non-positive weight", table.Depth)
+			}
+		}
+	}
+	return nil
+}
+
+// placeObjective gives regular floors an objective from the table of their
+// depth. Runes and switches go in empty rooms the player can reach without
+// keys.
+func placeObjective(rng *rand.Rand, f *floor, start image.Point, floorNum int) {
+	table := objectiveTableFor(floorNum)
+	if f.boss != "" || len(table.Objectives) == 0 || rng.Float64() >= table.Chance {
+		return
+	}
+
+	totalWeight := 0
+	for _, entry := range table.Objectives {
+		totalWeight += entry.Weight
+	}
+	pick := rng.Intn(totalWeight)
+	var entry ObjectiveEntry
+	for _, entry = range table.Objectives {
+		if pick < entry.Weight {
+			break
+		}
+		pick -= entry.Weight
+	}
+
+	open := reachableFrom(f.worldMap, start, false)
+	var spots []image.Point
+	for _, coord := range freeRooms(f.worldMap, start) {
+		if r := f.worldMap[coord.Y][coord.X]; open[coord] && !r.Hidden && !r.HasKey {
+			spots = append(spots, coord)
+		}
+	}
+	rng.Shuffle(len(spots), func(i, j int) { spots[i], spots[j] = spots[j], spots[i] })
+
+	switch entry.Type {
+	case objectiveRune:
+		if len(spots) == 0 {
+			return
+		}
+		f.worldMap[spots[0].Y][spots[0].X].HasRune = true
+	case objectiveSwitches:
+		if len(spots) < entry.Count {
+			return
+		}
+		for _, spot := range spots[:entry.Count] {
+			f.worldMap[spot.Y][spot.X].Switch = true
+		}
+	}
+	f.objective = &objective{kind: entry.Type, count: entry.Count}
+}
+
+// objectiveLeft is what is still to be done: enemies to defeat, the rune to
+// find or switches to activate.
+func (f floor) objectiveLeft() int {
+	left := 0
+	switch f.objective.kind {
+	case objectiveSlay:
+		left = len(f.wanderers)
+		for _, coord := range roomCoords(f.worldMap) {
+			if f.worldMap[coord.Y][coord.X].Type == Enemy {
+				left++
+			}
+		}
+	case objectiveRune:
+		for _, coord := range roomCoords(f.worldMap) {
+			if f.worldMap[coord.Y][coord.X].HasRune {
+				left++
+			}
+		}
+	case objectiveSwitches:
+		left = f.objective.count
+		for _, coord := range roomCoords(f.worldMap) {
+			if r := f.worldMap[coord.Y][coord.X]; r.Switch && r.SwitchOn {
+				left--
+			}
+		}
+	}
+	return left
+}
+
+// updateObjective marks the objective done once nothing is left to do. It
+// runs from Update whenever the objective may have moved on, so rooms that
+// respawn later cannot seal the stairs again.
+func (f floor) updateObjective() {
+	if f.objective != nil && !f.objective.done && f.objectiveLeft() <= 0 {
+		f.objective.done = true
+	}
+}
+
+// objectiveDone reports whether the stairs of the floor are open.
+func (f floor) objectiveDone() bool {
+	return f.objective == nil || f.objective.done
+}
+
+// objectiveText is the line about the objective in the camera panel.
+func (f floor) objectiveText() string {
+	if f.objective == nil {
+		return ""
+	}
+	if f.objectiveDone() {
+		return "Objective complete: the stairs are open."
+	}
+	left := f.objectiveLeft()
+	switch f.objective.kind {
+	case objectiveSlay:
+		return fmt.Sprintf("Objective: defeat every enemy (%d left).", left)
+	case objectiveRune:
+		return "Objective: find the rune hidden on this floor."
+	case objectiveSwitches:
+		return fmt.Sprintf("Objective: activate the switches (%d/%d).", f.objective.count-left, f.objective.count)
+	}
+	return ""
+}
+
+// activateSwitch pulls the switch of the current room.
+func (m model) activateSwitch(r *room) model {
+	r.SwitchOn = true
+	f := m.floors[m.currentFloor]
+	f.updateObjective()
+	if f.objectiveDone() {
+		m.message = "You pull the last lever. The stairs rumble open!"
+		return m
+	}
+	m.message = fmt.Sprintf("You pull the lever. Somewhere, a mechanism clicks. (%d left)", f.objectiveLeft())
+	return m
+}
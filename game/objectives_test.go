@@ -0,0 +1,29 @@
+package game
+
+import (
+	"image"
+	"testing"
+)
+
+func TestObjectiveLatchesInUpdate(t *testing.T) {
+	worldMap := newWorldMap(2, 1)
+	worldMap[0][0], worldMap[0][1] = &room{}, &room{}
+	connectRooms(worldMap, image.Point{X: 0, Y: 0}, image.Point{X: 1, Y: 0})
+	f := floor{worldMap: worldMap, objective: &objective{kind: objectiveSlay}}
+
+	// Drawing the camera panel must not complete the objective.
+	f.objectiveText()
+	if f.objective.done {
+		t.Fatal("objectiveText marked the objective done")
+	}
+
+	f.updateObjective()
+	if !f.objectiveDone() {
+		t.Fatal("the objective is not done with no enemies left")
+	}
+	// A room that respawns afterwards does not seal the stairs again.
+	worldMap[0][1].Type = Enemy
+	if !f.objectiveDone() {
+		t.Fatal("a respawned enemy sealed the stairs again")
+	}
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FloorParams controls how a floor is generated. Each entry of
+// data/floors.json applies from its depth until the next entry.
+type FloorParams struct {
+	Depth         int      `json:"depth"`
+	Width         int      `json:"width"`
+	Height        int      `json:"height"`
+	Rooms         int      `json:"rooms"`
+	EnemyRatioMin float64  `json:"enemyRatioMin"`
+	EnemyRatioMax float64  `json:"enemyRatioMax"`
+	TreasuresMin  int      `json:"treasuresMin"`
+	TreasuresMax  int      `json:"treasuresMax"`
+	ShopChance    float64  `json:"shopChance"`
+	LockedDoors   int      `json:"lockedDoors"`
+	SecretRooms   int      `json:"secretRooms"`
+	Traps         int      `json:"traps"`
+	BossEvery     int      `json:"bossEvery"`
+	Wanderers     int      `json:"wanderers"`
+	Teleporters   int      `json:"teleporters"`
+	Pits          int      `json:"pits"`
+	PitDrop       int      `json:"pitDrop"`
+	Generators    []string `json:"generators"`
+}
+
+func floorParamsFor(depth int) FloorParams {
+	params := FloorProgression[0]
+	for _, entry := range FloorProgression {
+		if entry.Depth > depth {
+			break
+		}
+		params = entry
+	}
+	return params
+}
+
+func validateProgression(progression []FloorParams) error {
+	if len(progression) == 0 {
+		return fmt.Errorf("floors: progression table is empty")
+	}
+
+	sort.Slice(progression, func(i, j int) bool {
+		return progression[i].Depth < progression[j].Depth
+	})
+	if progression[0].Depth != 0 {
+		return fmt.Errorf("floors: the first entry must start at depth 0")
+	}
+
+	for _, p := range progression {
+		if p.Width < 3 || p.Height < 3 || p.Rooms < 4 {
+			return fmt.Errorf("floors: depth %d needs at least a 3x3 grid and 4 rooms", p.Depth)
+		}
+		if p.EnemyRatioMin < 0 || p.EnemyRatioMax < p.EnemyRatioMin || p.EnemyRatioMax > 1 {
+			return fmt.Errorf("floors: depth %d has an invalid enemy ratio range", p.Depth)
+		}
+		if p.TreasuresMin < 0 || p.TreasuresMax < p.TreasuresMin {
+			return fmt.Errorf("floors: depth %d has an invalid treasure range", p.Depth)
+		}
+		if p.BossEvery < 0 {
+			return fmt.Errorf("floors: depth %d has a negative bossEvery", p.Depth)
+		}
+		if p.Wanderers < 0 {
+			return fmt.Errorf("floors: depth %d has a negative number of wanderers", p.Depth)
+		}
+		if p.Teleporters < 0 || p.Pits < 0 || p.PitDrop < 0 {
+			return fmt.Errorf("floors: depth %d has a negative number of teleporters or pits", p.Depth)
+		}
+		for _, name := range p.Generators {
+			if _, ok := mapGenerators[name]; !ok {
+				return fmt.Errorf("floors: depth %d uses unknown generator %q", p.Depth, name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RespawnRule is an entry of data/respawn.json, keyed by room kind (see
+// roomTypeKey). A room the player emptied gets its contents back After floor
+// turns with the given Chance; when the roll fails it waits After turns more.
+// After 0 means the room never comes back.
+type RespawnRule struct {
+	After  int     `json:"after"`
+	Chance float64 `json:"chance"`
+}
+
+func validateRespawnRules(rules map[string]RespawnRule) error {
+	for kind, rule := range rules {
+		if rule.After < 0 || rule.Chance < 0 || rule.Chance > 1 {
+			return fmt.Errorf("respawn: %s has an invalid rule", kind)
+		}
+	}
+	return nil
+}
+
+// clear records that the player emptied a room holding was at turn.
+func (r *room) clear(was roomType, turn int) {
+	r.Was = was
+	r.ClearedAt = turn
+}
+
+// respawnRooms brings back the contents of emptied rooms whose time has
+// come. The room the player stands in is left alone.
+func (m model) respawnRooms() {
+	f := m.floors[m.currentFloor]
+	for _, coord := range roomCoords(f.worldMap) {
+		r := f.worldMap[coord.Y][coord.X]
+		if r.Was == Empty || (coord.X == m.playerMapX && coord.Y == m.playerMapY) {
+			continue
+		}
+		rule, ok := RespawnTemplates[roomTypeKey(r.Was)]
+		if !ok || rule.After == 0 || f.turn-r.ClearedAt < rule.After {
+			continue
+		}
+		if rand.Float64() >= rule.Chance {
+			r.ClearedAt = f.turn
+			continue
+		}
+
+		switch r.Was {
+		case Shop:
+			r.restock(roomSeed(f.seed, coord.X, coord.Y) + int64(f.turn))
+		default:
+			if r.Type != Empty {
+				continue
+			}
+			r.Type = r.Was
+		}
+		r.Was = Empty
+	}
+}
+
+// arriveOnFloor catches the floor clock up with the time the player spent on
+// other floors, so backtracking finds the changes that happened meanwhile.
+func (m model) arriveOnFloor() model {
+	f := &m.floors[m.currentFloor]
+	f.turn += m.clock - f.leftAt
+	m.respawnRooms()
+	return m
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+const (
+	// restTurns is how long a rest takes on the floor clock.
+	restTurns = 10
+	restHP    = 25
+	restMana  = 15
+
+	maxPlayerMana = 50
+)
+
+// advanceClock moves the floor clock forward. Every step counts as a turn.
+// Emptied rooms may respawn, and wandering monsters move once per turn until
+// one of them finds the player.
+func (m model) advanceClock(turns int, resting bool) model {
+	for range turns {
+		m.clock++
+		m.floors[m.currentFloor].turn++
+		m.respawnRooms()
+		if m.state == StateCombat {
+			continue
+		}
+		m = m.meetWanderers(resting)
+		if m.state == StateCombat {
+			continue
+		}
+		m.moveWanderers()
+		m = m.meetWanderers(resting)
+	}
+	return m
+}
+
+// ambushChance is the percentage of being attacked while resting. It grows
+// with depth.
+func ambushChance(depth int) int {
+	return min(60, 10+depth*5)
+}
+
+// rest recovers HP and mana in an empty room. Resting takes time, and the
+// deeper the floor the more likely it is that something finds the player
+// asleep and strikes first.
+func (m model) rest() model {
+	currentRoom := m.floors[m.currentFloor].worldMap[m.playerMapY][m.playerMapX]
+	if currentRoom.Type != Empty {
+		m.message = "You can't rest here."
+		return m
+	}
+	if m.player.stats.hp >= 100 && m.player.stats.mana >= maxPlayerMana {
+		m.message = "You are already well rested."
+		return m
+	}
+
+	m = m.advanceClock(restTurns, true)
+	if m.state == StateCombat {
+		return m
+	}
+
+	if rand.Intn(100) < ambushChance(m.currentFloor) {
+		pool := m.enemyPool()
+		enemies := make([]*Foe, 1+rand.Intn(2))
+		for i := range enemies {
+			enemies[i] = newFoe(pool[rand.Intn(len(pool))])
+		}
+		m.message = "You are ambushed in your sleep!"
+		return m.startCombat(enemies, true)
+	}
+
+	hp := min(restHP, 100-m.player.stats.hp)
+	mana := min(restMana, max(0, maxPlayerMana-m.player.stats.mana))
+	m.player.stats.hp += hp
+	m.player.stats.mana += mana
+	m.message = fmt.Sprintf("You rest for a while. +%d HP, +%d mana.", hp, mana)
+	return m
+}
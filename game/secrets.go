@@ -0,0 +1,72 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+)
+
+// placeSecretRooms adds up to count hidden rooms on empty cells next to the
+// floor. Each one is joined to a single existing room and holds a chest from
+// the "secret" loot table.
+func placeSecretRooms(rng *rand.Rand, worldMap [][]*room, start image.Point, count int) {
+	for range count {
+		type spot struct {
+			cell, parent image.Point
+		}
+		var spots []spot
+		for _, coord := range roomCoords(worldMap) {
+			parent := worldMap[coord.Y][coord.X]
+			if coord == start || parent.Secret {
+				continue
+			}
+			for _, offset := range cardinalDirections {
+				cell := coord.Add(offset)
+				if inBounds(worldMap, cell.X, cell.Y) && worldMap[cell.Y][cell.X] == nil {
+					spots = append(spots, spot{cell: cell, parent: coord})
+				}
+			}
+		}
+		if len(spots) == 0 {
+			return
+		}
+
+		chosen := spots[rng.Intn(len(spots))]
+		worldMap[chosen.cell.Y][chosen.cell.X] = &room{
+			Type:      Tresure,
+			Secret:    true,
+			Hidden:    true,
+			LootTable: "secret",
+		}
+		connectRooms(worldMap, chosen.parent, chosen.cell)
+	}
+}
+
+// searchChance is the percentage of finding each hidden neighbor per search.
+func (m model) searchChance() int {
+	return min(90, 20+m.player.stats.magic*3)
+}
+
+func (m model) searchRoom() model {
+	currentMap := m.floors[m.currentFloor].worldMap
+	current := currentMap[m.playerMapY][m.playerMapX]
+
+	found := false
+	for dir, exit := range current.Exits {
+		if exit == nil {
+			continue
+		}
+		neighbor := currentMap[m.playerMapY+cardinalDirections[dir].Y][m.playerMapX+cardinalDirections[dir].X]
+		if neighbor.Hidden && rand.Intn(100) < m.searchChance() {
+			neighbor.Hidden = false
+			neighbor.Seen = true
+			found = true
+		}
+	}
+
+	if found {
+		m.message = "You search the walls... and find a hidden passage!"
+	} else {
+		m.message = "You search the walls but find nothing."
+	}
+	return m
+}
@@ -0,0 +1,124 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+)
+
+// pitDamage is the HP lost for every floor a pit drops the player.
+const pitDamage = 8
+
+// freeRooms lists the empty rooms other than start.
+func freeRooms(worldMap [][]*room, start image.Point) []image.Point {
+	var spots []image.Point
+	for _, coord := range roomCoords(worldMap) {
+		if coord != start && worldMap[coord.Y][coord.X].Type == Empty {
+			spots = append(spots, coord)
+		}
+	}
+	return spots
+}
+
+// placeTeleporters turns pairs of empty rooms into teleporters linked to each
+// other. The second room of a pair is the free room farthest from the first.
+func placeTeleporters(rng *rand.Rand, worldMap [][]*room, start image.Point, pairs int) {
+	for range pairs {
+		spots := freeRooms(worldMap, start)
+		if len(spots) < 2 {
+			return
+		}
+		a := spots[rng.Intn(len(spots))]
+		distances := roomDistances(worldMap, a)
+		b := a
+		for _, spot := range spots {
+			if distances[spot] > distances[b] {
+				b = spot
+			}
+		}
+		if b == a {
+			return
+		}
+		worldMap[a.Y][a.X].Type, worldMap[a.Y][a.X].Link = Teleporter, b
+		worldMap[b.Y][b.X].Type, worldMap[b.Y][b.X].Link = Teleporter, a
+	}
+}
+
+// placePits turns empty rooms into pits dropping 1 to maxDrop floors.
+func placePits(rng *rand.Rand, worldMap [][]*room, start image.Point, count, maxDrop int) {
+	for range count {
+		spots := freeRooms(worldMap, start)
+		if len(spots) == 0 {
+			return
+		}
+		spot := spots[rng.Intn(len(spots))]
+		worldMap[spot.Y][spot.X].Type = Pit
+		worldMap[spot.Y][spot.X].Drop = 1 + rng.Intn(max(1, maxDrop))
+	}
+}
+
+// teleport takes the player to the other teleporter of the pair. Arriving
+// there is handled like any other move.
+func (m model) teleport(r *room) model {
+	m.playerMapX, m.playerMapY = r.Link.X, r.Link.Y
+	m.message = "The teleporter hums and the world spins around you."
+	return m
+}
+
+// jumpIntoPit drops the player into a random room of a deeper floor,
+// generating the floors it falls past. Pits never drop past the next boss
+// floor, so bosses cannot be skipped.
+func (m model) jumpIntoPit(r *room) model {
+	target := m.currentFloor + r.Drop
+	for depth := m.currentFloor + 1; depth < target; depth++ {
+		if isBossFloor(floorParamsFor(depth), depth) {
+			target = depth
+			break
+		}
+	}
+	fallen := target - m.currentFloor
+
+	m.floors[m.currentFloor].leftAt = m.clock
+	visited := target < len(m.floors)
+	for len(m.floors) <= target {
+		next, _, _ := newFloor(m.runSeed, len(m.floors))
+		next.leftAt = m.clock
+		m.floors = append(m.floors, *next)
+	}
+	m.currentFloor = target
+	if visited {
+		m = m.arriveOnFloor()
+	}
+
+	f := m.floors[m.currentFloor]
+	worldMap := f.worldMap
+	var landings []image.Point
+	for _, coord := range roomCoords(worldMap) {
+		if landing := worldMap[coord.Y][coord.X]; landing.Type == Empty && !landing.Hidden && !f.wandererAt(coord.X, coord.Y) {
+			landings = append(landings, coord)
+		}
+	}
+	if len(landings) == 0 {
+		for _, coord := range roomCoords(worldMap) {
+			if landing := worldMap[coord.Y][coord.X]; !landing.Hidden && landing.Type != Boss {
+				landings = append(landings, coord)
+			}
+		}
+	}
+	landing := landings[rand.New(rand.NewSource(floorSeed(f.seed, -3))).Intn(len(landings))]
+	m.playerMapX, m.playerMapY = landing.X, landing.Y
+
+	damage := pitDamage * fallen
+	player := &Player{data: &m.player}
+	player.ModifyStat("HP", -damage)
+	if m.player.stats.hp <= 0 {
+		m.state = StateMenu
+		return m
+	}
+
+	m = m.enterRoom()
+	if m.message == "" {
+		m.message = fmt.Sprintf("You fall %d floor(s) and land hard. -%d HP.", fallen, damage)
+	}
+	return m.advanceClock(1, false)
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// restock fills the shelves of a shop from its loot table.
+func (r *room) restock(seed int64) {
+	table, ok := LootTemplates[r.LootTable]
+	if !ok {
+		table = LootTemplates["shop"]
+	}
+	_, r.Stock = table.roll(rand.New(rand.NewSource(seed)))
+}
+
+// stockIDs lists the items a shop sells in the order they are shown.
+func (r *room) stockIDs() []string {
+	ids := make([]string, 0, len(r.Stock))
+	for id, count := range r.Stock {
+		if count > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// buy sells the player the n-th item of the shop in the current room. An
+// emptied shelf counts towards the shop restock.
+func (m model) buy(n int) model {
+	f := m.floors[m.currentFloor]
+	r := f.worldMap[m.playerMapY][m.playerMapX]
+	ids := r.stockIDs()
+	if n >= len(ids) {
+		return m
+	}
+
+	item := ItemTemplates[ids[n]]
+	if m.player.gold < item.Price {
+		m.message = fmt.Sprintf("You need %d gold for the %s.", item.Price, item.Name)
+		return m
+	}
+	m.player.gold -= item.Price
+	m.player.inventory[ids[n]]++
+	r.Stock[ids[n]]--
+	if r.Was != Shop {
+		r.clear(Shop, f.turn)
+	}
+	m.message = fmt.Sprintf("You buy a %s for %d gold.", item.Name, item.Price)
+	return m
+}
+
+func (m model) renderStock(r *room) string {
+	ids := r.stockIDs()
+	if len(ids) == 0 {
+		return "'Sold out! Come back later.'"
+	}
+	lines := make([]string, len(ids))
+	for i, id := range ids {
+		item := ItemTemplates[id]
+		lines[i] = fmt.Sprintf("%d) %s x%d - %d gold", i+1, item.Name, r.Stock[id], item.Price)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -2,8 +2,6 @@ package game
 
 import (
 	"github.com/charmbracelet/lipgloss"
-	"github.com/charmbracelet/ssh"
-	"github.com/charmbracelet/wish/bubbletea"
 )
 
 var (
@@ -23,11 +21,16 @@ type styles struct {
 	Player      lipgloss.Style
 	Room        lipgloss.Style
 	RoomSpecial lipgloss.Style
+	Locked      lipgloss.Style
+	RoomSecret  lipgloss.Style
 	StatsArt    lipgloss.Style
+	RoomBoss    lipgloss.Style
+	BossPanel   lipgloss.Style
+	Wanderer    lipgloss.Style
+	Marked      lipgloss.Style
 }
 
-func newStyles(s ssh.Session) styles {
-	renderer := bubbletea.MakeRenderer(s)
+func newStyles(renderer *lipgloss.Renderer) styles {
 	return styles{
 		Title:       renderer.NewStyle().Foreground(orange).Bold(true),
 		Selected:    renderer.NewStyle().Foreground(indigo).Bold(true),
@@ -38,6 +41,12 @@ func newStyles(s ssh.Session) styles {
 		Player:      renderer.NewStyle().Width(3).Align(lipgloss.Center).Foreground(orange).SetString("[@]"),
 		Room:        renderer.NewStyle().Width(3).Align(lipgloss.Center),
 		RoomSpecial: renderer.NewStyle().Foreground(indigo),
+		Locked:      renderer.NewStyle().Foreground(orange),
+		RoomSecret:  renderer.NewStyle().Foreground(orange).Italic(true),
 		StatsArt:    renderer.NewStyle().Foreground(orange).Bold(true).Margin(1, 2),
+		RoomBoss:    renderer.NewStyle().Foreground(orange).Bold(true),
+		BossPanel:   renderer.NewStyle().Border(lipgloss.ThickBorder()).BorderForeground(orange).Padding(1, 4).Align(lipgloss.Center),
+		Wanderer:    renderer.NewStyle().Foreground(orange).Bold(true),
+		Marked:      renderer.NewStyle().Foreground(orange).Underline(true),
 	}
 }
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is a floor biome from data/themes.json. It applies to floors from
+// MinDepth to MaxDepth (0 means no limit) and picks their enemies, layouts,
+// room descriptions and colors. Descriptions are templates like the ones in
+// data/descriptions.json and Fragments add to or replace its fragments.
+type Theme struct {
+	Name         string              `json:"name"`
+	MinDepth     int                 `json:"minDepth"`
+	MaxDepth     int                 `json:"maxDepth"`
+	Enemies      []string            `json:"enemies"`
+	Generators   []string            `json:"generators"`
+	Palette      Palette             `json:"palette"`
+	Descriptions map[string][]string `json:"descriptions"`
+	Fragments    map[string][]string `json:"fragments"`
+}
+
+// Palette overrides the colors of a themed floor. Empty colors keep the
+// default ones.
+type Palette struct {
+	Accent string `json:"accent"`
+	Border string `json:"border"`
+}
+
+func (t Theme) allows(depth int) bool {
+	return depth >= t.MinDepth && (t.MaxDepth == 0 || depth <= t.MaxDepth)
+}
+
+// themeForFloor picks one of the themes allowed at depth, or "" if none is.
+// The seed is mixed so the choice does not follow the generator choice.
+func themeForFloor(seed int64, depth int) string {
+	var ids []string
+	for id, theme := range ThemeTemplates {
+		if theme.allows(depth) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Strings(ids)
+	return ids[rand.New(rand.NewSource(floorSeed(seed, depth))).Intn(len(ids))]
+}
+
+func validateThemes(themes map[string]Theme) error {
+	for id, theme := range themes {
+		if theme.MaxDepth != 0 && theme.MaxDepth < theme.MinDepth {
+			return fmt.Errorf("themes: %s has an invalid depth range", id)
+		}
+		for _, enemy := range theme.Enemies {
+			if _, ok := EnemyTemplates[enemy]; !ok {
+				return fmt.Errorf("themes: %s uses unknown enemy %q", id, enemy)
+			}
+		}
+		for _, name := range theme.Generators {
+			if _, ok := mapGenerators[name]; !ok {
+				return fmt.Errorf("themes: %s uses unknown generator %q", id, name)
+			}
+		}
+	}
+	return nil
+}
+
+// enemyPool lists the enemies that can appear on the current floor.
+func (m model) enemyPool() []string {
+	return themeEnemies(m.floors[m.currentFloor].theme)
+}
+
+func themeEnemies(id string) []string {
+	if theme, ok := ThemeTemplates[id]; ok && len(theme.Enemies) > 0 {
+		return theme.Enemies
+	}
+	return []string{"goblin"}
+}
+
+func roomTypeKey(t roomType) string {
+	switch t {
+	case Enemy:
+		return "enemy"
+	case Tresure:
+		return "treasure"
+	case Shop:
+		return "shop"
+	case StairsUp:
+		return "stairsUp"
+	case StairsDown:
+		return "stairsDown"
+	case Trap:
+		return "trap"
+	case Boss:
+		return "boss"
+	case Teleporter:
+		return "teleporter"
+	case Pit:
+		return "pit"
+	}
+	return "empty"
+}
+
+// withPalette returns a copy of the styles using the colors of p.
+func (s styles) withPalette(p Palette) styles {
+	if p.Accent != "" {
+		accent := lipgloss.Color(p.Accent)
+		s.Title = s.Title.Foreground(accent)
+		s.Help = s.Help.Foreground(accent)
+		s.Player = s.Player.Foreground(accent)
+		s.StatsArt = s.StatsArt.Foreground(accent)
+		s.RoomBoss = s.RoomBoss.Foreground(accent)
+	}
+	if p.Border != "" {
+		border := lipgloss.Color(p.Border)
+		s.Panel = s.Panel.BorderForeground(border)
+		s.MapBorder = s.MapBorder.BorderForeground(border)
+		s.RoomSpecial = s.RoomSpecial.Foreground(border)
+	}
+	return s
+}
+
+// floorStyles are the styles for the current floor, with its theme colors.
+func (m model) floorStyles() styles {
+	if len(m.floors) == 0 {
+		return m.styles
+	}
+	if theme, ok := ThemeTemplates[m.floors[m.currentFloor].theme]; ok {
+		return m.styles.withPalette(theme.Palette)
+	}
+	return m.styles
+}
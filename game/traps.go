@@ -0,0 +1,133 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"sort"
+)
+
+// TrapTemplate is a trap from data/traps.json. Effects are applied to the
+// player like attack effects with target "Self". A trap can also teleport the
+// player to a random room or spring an ambush with the listed enemies.
+type TrapTemplate struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	DetectStat  string   `json:"detectStat"`
+	DisarmStat  string   `json:"disarmStat"`
+	Difficulty  int      `json:"difficulty"`
+	Effects     []Effect `json:"effects"`
+	Teleport    bool     `json:"teleport"`
+	Ambush      []string `json:"ambush"`
+}
+
+func trapIDs() []string {
+	ids := make([]string, 0, len(TrapTemplates))
+	for id := range TrapTemplates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (p playerStats) get(stat string) int {
+	switch stat {
+	case "HP":
+		return p.hp
+	case "Mana":
+		return p.mana
+	case "Speed":
+		return p.speed
+	case "Magic":
+		return p.magic
+	case "Strength":
+		return p.strength
+	case "Defense":
+		return p.defense
+	}
+	return 0
+}
+
+// statCheck rolls a d20 plus half of the stat against the difficulty.
+func (m model) statCheck(stat string, difficulty int) bool {
+	return rand.Intn(20)+1+m.player.stats.get(stat)/2 >= difficulty
+}
+
+func (m model) enterTrapRoom(r *room) model {
+	trap, ok := TrapTemplates[r.Trap]
+	if !ok {
+		r.Type = Empty
+		return m
+	}
+	if r.TrapDetected {
+		m.message = fmt.Sprintf("You carefully avoid the %s.", trap.Name)
+		return m
+	}
+
+	if m.statCheck(trap.DetectStat, trap.Difficulty) {
+		r.TrapDetected = true
+		m.message = fmt.Sprintf("You spot a %s just in time!", trap.Name)
+		return m
+	}
+
+	m.message = fmt.Sprintf("You triggered a %s!", trap.Name)
+	return m.triggerTrap(r, trap)
+}
+
+func (m model) disarmTrap(r *room) model {
+	trap, ok := TrapTemplates[r.Trap]
+	if !ok || !r.TrapDetected {
+		return m
+	}
+
+	if m.statCheck(trap.DisarmStat, trap.Difficulty) {
+		r.Type = Empty
+		r.Trap = ""
+		m.message = fmt.Sprintf("You disarm the %s.", trap.Name)
+		return m
+	}
+
+	m.message = fmt.Sprintf("Your hand slips and the %s goes off!", trap.Name)
+	return m.triggerTrap(r, trap)
+}
+
+// triggerTrap applies the trap once; spent traps leave an empty room.
+func (m model) triggerTrap(r *room, trap TrapTemplate) model {
+	r.Type = Empty
+	r.Trap = ""
+
+	player := &Player{data: &m.player}
+	m.applyEffects(player, nil, trap.Effects)
+	if m.player.stats.hp <= 0 {
+		m.state = StateMenu
+		return m
+	}
+
+	if len(trap.Ambush) > 0 {
+		enemies := make([]*Foe, 0, len(trap.Ambush))
+		for _, id := range trap.Ambush {
+			enemies = append(enemies, newFoe(id))
+		}
+		return m.startCombat(enemies, true)
+	}
+
+	if trap.Teleport {
+		currentMap := m.floors[m.currentFloor].worldMap
+		var destinations []image.Point
+		for _, coord := range roomCoords(currentMap) {
+			if !currentMap[coord.Y][coord.X].Hidden && (coord.X != m.playerMapX || coord.Y != m.playerMapY) {
+				destinations = append(destinations, coord)
+			}
+		}
+		if len(destinations) > 0 {
+			dest := destinations[rand.Intn(len(destinations))]
+			m.playerMapX, m.playerMapY = dest.X, dest.Y
+			message := m.message
+			m = m.enterRoom()
+			if m.message == "" {
+				m.message = message
+			}
+		}
+	}
+	return m
+}
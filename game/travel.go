@@ -0,0 +1,245 @@
+package game
+
+import (
+	"image"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const travelStepDelay = 120 * time.Millisecond
+
+// travelStepMsg walks the player one room along m.travelPath. Steps of an
+// earlier travel are ignored.
+type travelStepMsg struct {
+	travel int
+}
+
+func (m model) travelTick() tea.Cmd {
+	travel := m.travelID
+	return tea.Tick(travelStepDelay, func(time.Time) tea.Msg {
+		return travelStepMsg{travel: travel}
+	})
+}
+
+// traveling reports whether the player is walking on their own.
+func (m model) traveling() bool {
+	return len(m.travelPath) > 0 || m.autoexploring
+}
+
+func (m model) stopTravel() model {
+	m.travelPath = nil
+	m.autoexploring = false
+	m.travelID++
+	return m
+}
+
+// findPath returns the exits to take from the player's room to reach goal
+// through rooms the player has seen. Rooms that were never visited can only
+// be the goal, locked doors are never opened and known dangers (monster
+// lairs, bosses and detected traps) are walked around.
+func (m model) findPath(goal image.Point) []direction {
+	worldMap := m.floors[m.currentFloor].worldMap
+	start := image.Point{X: m.playerMapX, Y: m.playerMapY}
+	if goal == start {
+		return nil
+	}
+
+	cameFrom := map[image.Point]direction{}
+	visited := map[image.Point]bool{start: true}
+	queue := []image.Point{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == goal {
+			break
+		}
+		if current != start && !worldMap[current.Y][current.X].Visited {
+			continue
+		}
+		for dir, exit := range worldMap[current.Y][current.X].Exits {
+			if exit == nil || exit.Locked {
+				continue
+			}
+			next := current.Add(cardinalDirections[dir])
+			r := worldMap[next.Y][next.X]
+			if visited[next] || r.Hidden || !r.Seen || (next != goal && r.isKnownDanger()) {
+				continue
+			}
+			visited[next] = true
+			cameFrom[next] = direction(dir)
+			queue = append(queue, next)
+		}
+	}
+
+	if !visited[goal] {
+		return nil
+	}
+	var path []direction
+	for p := goal; p != start; {
+		dir := cameFrom[p]
+		path = append([]direction{dir}, path...)
+		p = p.Sub(cardinalDirections[dir])
+	}
+	return path
+}
+
+func (r *room) isKnownDanger() bool {
+	if !r.Visited {
+		return false
+	}
+	return r.Type == Enemy || r.Type == Boss || (r.Type == Trap && r.TrapDetected)
+}
+
+// travelTo starts walking to a visited room.
+func (m model) travelTo(goal image.Point) (model, tea.Cmd) {
+	m = m.stopTravel()
+	r := m.floors[m.currentFloor].worldMap[goal.Y][goal.X]
+	if r == nil || !r.Visited {
+		m.message = "You can only travel to rooms you have visited."
+		return m, nil
+	}
+	if m.zoomed {
+		m.message = "Zoom out to travel."
+		return m, nil
+	}
+	if m.travelPath = m.findPath(goal); m.travelPath == nil {
+		if goal.X != m.playerMapX || goal.Y != m.playerMapY {
+			m.message = "You don't know a safe way there."
+		}
+		return m, nil
+	}
+	return m, m.travelTick()
+}
+
+// autoexplore walks to the nearest room the player has seen but not
+// visited, again and again, until something happens.
+func (m model) autoexplore() (model, tea.Cmd) {
+	m = m.stopTravel()
+	if m.zoomed {
+		m.message = "Zoom out to explore."
+		return m, nil
+	}
+	if m.travelPath = m.nextUnexplored(); m.travelPath == nil {
+		m.message = "Nothing left to explore that you can safely reach."
+		return m, nil
+	}
+	m.autoexploring = true
+	return m, m.travelTick()
+}
+
+// nextUnexplored is the shortest path to a seen room that was not visited.
+func (m model) nextUnexplored() []direction {
+	var best []direction
+	for _, coord := range roomCoords(m.floors[m.currentFloor].worldMap) {
+		r := m.floors[m.currentFloor].worldMap[coord.Y][coord.X]
+		if r.Visited || !r.Seen || r.Hidden {
+			continue
+		}
+		if path := m.findPath(coord); path != nil && (best == nil || len(path) < len(best)) {
+			best = path
+		}
+	}
+	return best
+}
+
+// travelStep walks one room. Travel stops when a fight starts, when anything
+// happens in the room (a chest, a trap, a key...) or when wandering monsters
+// are in sight.
+func (m model) travelStep(msg travelStepMsg) (model, tea.Cmd) {
+	if msg.travel != m.travelID {
+		return m, nil
+	}
+	if m.state != StateGame || !m.traveling() || m.showFullMap {
+		return m.stopTravel(), nil
+	}
+	if len(m.travelPath) == 0 {
+		if m.travelPath = m.nextUnexplored(); m.travelPath == nil {
+			m.message = "Nothing left to explore that you can safely reach."
+			return m.stopTravel(), nil
+		}
+	}
+
+	m.message = ""
+	dir := m.travelPath[0]
+	m.travelPath = m.travelPath[1:]
+	prevX, prevY := m.playerMapX, m.playerMapY
+	m = m.movePlayer(dir)
+	if prevX == m.playerMapX && prevY == m.playerMapY {
+		return m.stopTravel(), nil
+	}
+	m = m.enterRoom()
+	m = m.advanceClock(1, false)
+
+	if m.state == StateCombat {
+		return m.stopTravel().openCombat()
+	}
+	if m.message != "" {
+		return m.stopTravel(), nil
+	}
+	if m.wanderersInSight() {
+		m.message = "You hear wandering monsters nearby!"
+		return m.stopTravel(), nil
+	}
+	if !m.traveling() {
+		return m, nil
+	}
+	return m, m.travelTick()
+}
+
+// wanderersInSight reports whether a group stands in a room next to the
+// player's.
+func (m model) wanderersInSight() bool {
+	f := m.floors[m.currentFloor]
+	for dir, exit := range f.worldMap[m.playerMapY][m.playerMapX].Exits {
+		next := image.Point{X: m.playerMapX, Y: m.playerMapY}.Add(cardinalDirections[dir])
+		if exit != nil && !f.worldMap[next.Y][next.X].Hidden && f.wandererAt(next.X, next.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+// mainMapSize is the room renderGameView leaves for the floor map.
+func (m model) mainMapSize() (int, int) {
+	mapFrame := m.styles.MapBorder.GetHorizontalFrameSize()
+	return m.width - minSidePanelWidth - mapFrame, m.height - mapFrame - 1
+}
+
+// clickedRoom returns the room under a click on the main map, following the
+// layout of renderGameView.
+func (m model) clickedRoom(msg tea.MouseMsg) (image.Point, bool) {
+	if m.zoomed {
+		return image.Point{}, false
+	}
+	maxWidth, maxHeight := m.mainMapSize()
+	player := image.Point{X: m.playerMapX, Y: m.playerMapY}
+	content := m.renderMapViewport(maxWidth, maxHeight, player)
+	inner := max(min(45, maxWidth), lipgloss.Width(content))
+
+	// The side panels take what the map panel leaves.
+	mapViewWidth := inner + m.styles.MapBorder.GetHorizontalFrameSize()
+	sideWidth := m.width - mapViewWidth - 4 + m.styles.Panel.GetHorizontalFrameSize()
+	x0 := sideWidth + m.styles.MapBorder.GetBorderLeftSize() + (inner-lipgloss.Width(content))/2
+	y0 := m.styles.MapBorder.GetBorderTopSize()
+	return m.mapViewport(maxWidth, maxHeight, player).roomAt(msg.X-x0, msg.Y-y0)
+}
+
+// fullMapClickedRoom returns the room under a click on the full map,
+// following the layout of renderFullMap.
+func (m model) fullMapClickedRoom(msg tea.MouseMsg) (image.Point, bool) {
+	title, mapView, sideView, help := m.fullMapParts()
+	bodyWidth := lipgloss.Width(mapView) + lipgloss.Width(sideView)
+	width := max(lipgloss.Width(title), bodyWidth, lipgloss.Width(help))
+	height := lipgloss.Height(title) + max(lipgloss.Height(mapView), lipgloss.Height(sideView)) + lipgloss.Height(help)
+
+	x0 := max(0, m.width-width)/2 + (width-bodyWidth)/2 + m.styles.MapBorder.GetBorderLeftSize()
+	y0 := max(0, m.height-height)/2 + lipgloss.Height(title) + m.styles.MapBorder.GetBorderTopSize()
+	maxWidth, maxHeight := m.fullMapSize(sideView)
+	return m.mapViewport(maxWidth, maxHeight, m.mapCursor).roomAt(msg.X-x0, msg.Y-y0)
+}
+
+func isLeftClick(msg tea.MouseMsg) bool {
+	return msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft
+}
@@ -0,0 +1,157 @@
+package game
+
+import (
+	"fmt"
+	"image"
+)
+
+// maxFloorAttempts is how many layouts newFloor tries before giving up on a
+// floor that breaks validateFloor.
+const maxFloorAttempts = 10
+
+// validateFloor checks the rules every generated floor must follow: all
+// rooms connected, one StairsUp, a StairsDown start above floor 0, a safe
+// start room, the shop next to enemies, the boss next to the stairs and every
+// key reachable before the doors it opens.
+func validateFloor(f *floor, start image.Point, floorNum int) error {
+	worldMap := f.worldMap
+	coords := roomCoords(worldMap)
+
+	if !inBounds(worldMap, start.X, start.Y) || worldMap[start.Y][start.X] == nil {
+		return fmt.Errorf("floor %d: start %v is not a room", floorNum, start)
+	}
+	if reachable := reachableFrom(worldMap, start, true); len(reachable) != len(coords) {
+		return fmt.Errorf("floor %d: %d of %d rooms are reachable", floorNum, len(reachable), len(coords))
+	}
+
+	counts := make(map[roomType]int)
+	var upStairs image.Point
+	for _, coord := range coords {
+		r := worldMap[coord.Y][coord.X]
+		counts[r.Type]++
+		switch r.Type {
+		case StairsUp:
+			upStairs = coord
+		case Teleporter:
+			if coord == start {
+				return fmt.Errorf("floor %d: the start room is a teleporter", floorNum)
+			}
+			if other := r.Link; !inBounds(worldMap, other.X, other.Y) || worldMap[other.Y][other.X] == nil ||
+				worldMap[other.Y][other.X].Type != Teleporter || worldMap[other.Y][other.X].Link != coord {
+				return fmt.Errorf("floor %d: the teleporter at %v is not paired", floorNum, coord)
+			}
+		case Pit:
+			if coord == start {
+				return fmt.Errorf("floor %d: the start room is a pit", floorNum)
+			}
+			if f.boss != "" {
+				return fmt.Errorf("floor %d: boss floors cannot have pits", floorNum)
+			}
+		case Shop:
+			if coord == start {
+				return fmt.Errorf("floor %d: the shop is the start room", floorNum)
+			}
+			if !isAdjacentToEnemy(coord.X, coord.Y, worldMap) && !isAdjacentTo(coord, worldMap, Boss) {
+				return fmt.Errorf("floor %d: the shop at %v has no enemy next to it", floorNum, coord)
+			}
+		}
+	}
+
+	if counts[StairsUp] != 1 {
+		return fmt.Errorf("floor %d: has %d StairsUp rooms", floorNum, counts[StairsUp])
+	}
+	if counts[Shop] > 1 {
+		return fmt.Errorf("floor %d: has %d shops", floorNum, counts[Shop])
+	}
+
+	startRoom := worldMap[start.Y][start.X]
+	if floorNum > 0 {
+		if counts[StairsDown] != 1 || startRoom.Type != StairsDown {
+			return fmt.Errorf("floor %d: must start on its only StairsDown", floorNum)
+		}
+	} else if counts[StairsDown] != 0 || startRoom.Type != Empty {
+		return fmt.Errorf("floor %d: must start in an empty room without StairsDown", floorNum)
+	}
+	if startRoom.Type == Enemy || startRoom.Hidden {
+		return fmt.Errorf("floor %d: unsafe start room", floorNum)
+	}
+
+	if f.boss != "" {
+		if counts[Boss] != 1 {
+			return fmt.Errorf("floor %d: has %d boss rooms", floorNum, counts[Boss])
+		}
+		if !isAdjacentTo(upStairs, worldMap, Boss) {
+			return fmt.Errorf("floor %d: the boss room is not next to the stairs", floorNum)
+		}
+	} else if counts[Boss] != 0 {
+		return fmt.Errorf("floor %d: has a boss room but no boss", floorNum)
+	}
+
+	if o := f.objective; o != nil {
+		switches, runes := 0, 0
+		for _, coord := range coords {
+			r := worldMap[coord.Y][coord.X]
+			if r.Switch || r.HasRune {
+				if !reachableFrom(worldMap, start, false)[coord] || coord == start || r.Type != Empty {
+					return fmt.Errorf("floor %d: objective room %v is out of reach", floorNum, coord)
+				}
+			}
+			if r.Switch {
+				switches++
+			}
+			if r.HasRune {
+				runes++
+			}
+		}
+		if (o.kind == objectiveSwitches && switches != o.count) || (o.kind == objectiveRune && runes != 1) {
+			return fmt.Errorf("floor %d: the %s objective is not set up", floorNum, o.kind)
+		}
+	}
+
+	keys, locks := 0, 0
+	open := reachableFrom(worldMap, start, false)
+	for _, coord := range coords {
+		if worldMap[coord.Y][coord.X].HasKey {
+			keys++
+			if !open[coord] {
+				return fmt.Errorf("floor %d: the key at %v is behind a locked door", floorNum, coord)
+			}
+		}
+	}
+	for _, edge := range floorEdges(worldMap) {
+		if edge.p.Locked {
+			locks++
+		}
+	}
+	if keys < locks {
+		return fmt.Errorf("floor %d: %d locked doors but only %d keys", floorNum, locks, keys)
+	}
+	return nil
+}
+
+func isAdjacentTo(coord image.Point, worldMap [][]*room, t roomType) bool {
+	for dir, exit := range worldMap[coord.Y][coord.X].Exits {
+		if exit == nil {
+			continue
+		}
+		next := coord.Add(cardinalDirections[dir])
+		if worldMap[next.Y][next.X].Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFloors generates the first depth floors of every seed in
+// [0, seeds) and reports the first one that breaks validateFloor.
+func CheckFloors(seeds int64, depth int) error {
+	for seed := range seeds {
+		for floorNum := range depth {
+			f, startX, startY := newFloor(seed, floorNum)
+			if err := validateFloor(f, image.Point{X: startX, Y: startY}, floorNum); err != nil {
+				return fmt.Errorf("seed %d: %w", seed, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// testDepth is how many floors of each run the tests generate, the same as
+// the default of -check-depth.
+const testDepth = 12
+
+func TestMain(m *testing.M) {
+	// The game data lives in data/ at the repository root. Fuzz workers
+	// start in the directory of their parent, so go there from this file
+	// rather than from the working directory.
+	_, file, _, _ := runtime.Caller(0)
+	if err := os.Chdir(filepath.Join(filepath.Dir(file), "..")); err != nil {
+		panic(err)
+	}
+	if err := LoadGameData(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// checkFloorInvariants fails t if the floor breaks one of the rules every
+// floor must follow, checking each of them on its own instead of trusting
+// validateFloor.
+func checkFloorInvariants(t testing.TB, f *floor, start image.Point, floorNum int) {
+	t.Helper()
+	worldMap := f.worldMap
+	if !inBounds(worldMap, start.X, start.Y) || worldMap[start.Y][start.X] == nil {
+		t.Fatalf("floor %d: start %v is not a room", floorNum, start)
+	}
+
+	coords := roomCoords(worldMap)
+	if reachable := reachableFrom(worldMap, start, true); len(reachable) != len(coords) {
+		t.Errorf("floor %d: %d of %d rooms are reachable from the start", floorNum, len(reachable), len(coords))
+	}
+
+	counts := make(map[roomType]int)
+	for _, coord := range coords {
+		r := worldMap[coord.Y][coord.X]
+		counts[r.Type]++
+		if r.Type != Shop {
+			continue
+		}
+		if coord == start {
+			t.Errorf("floor %d: the shop is the start room", floorNum)
+		}
+		if !isAdjacentToEnemy(coord.X, coord.Y, worldMap) && !isAdjacentTo(coord, worldMap, Boss) {
+			t.Errorf("floor %d: the shop at %v has no enemy next to it", floorNum, coord)
+		}
+	}
+	if counts[StairsUp] != 1 {
+		t.Errorf("floor %d: %d StairsUp rooms, want 1", floorNum, counts[StairsUp])
+	}
+	if counts[Shop] > 1 {
+		t.Errorf("floor %d: %d shops, want at most 1", floorNum, counts[Shop])
+	}
+
+	startRoom := worldMap[start.Y][start.X]
+	if floorNum > 0 && (counts[StairsDown] != 1 || startRoom.Type != StairsDown) {
+		t.Errorf("floor %d: %d StairsDown rooms and a %v start, want one StairsDown as the start", floorNum, counts[StairsDown], startRoom.Type)
+	}
+	if floorNum == 0 && counts[StairsDown] != 0 {
+		t.Errorf("floor 0: %d StairsDown rooms, want none", counts[StairsDown])
+	}
+	if startRoom.Type == Enemy {
+		t.Errorf("floor %d: the start room is an enemy room", floorNum)
+	}
+
+	if err := validateFloor(f, start, floorNum); err != nil {
+		t.Errorf("validateFloor: %v", err)
+	}
+}
+
+func TestNewFloorInvariants(t *testing.T) {
+	for runSeed := range int64(200) {
+		for floorNum := range testDepth {
+			f, startX, startY := newFloor(runSeed, floorNum)
+			checkFloorInvariants(t, f, image.Point{X: startX, Y: startY}, floorNum)
+			if t.Failed() {
+				t.Fatalf("run seed %d, floor %d", runSeed, floorNum)
+			}
+		}
+	}
+}
+
+func TestFallbackFloor(t *testing.T) {
+	for floorNum := range testDepth {
+		f, startX, startY := fallbackFloor(1, floorNum)
+		checkFloorInvariants(t, f, image.Point{X: startX, Y: startY}, floorNum)
+	}
+}
+
+func FuzzGenerateMap(f *testing.F) {
+	for _, seed := range []int64{0, 1, 42, -1, 1 << 62} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, runSeed int64) {
+		for floorNum := range testDepth {
+			fl, startX, startY := newFloor(runSeed, floorNum)
+			checkFloorInvariants(t, fl, image.Point{X: startX, Y: startY}, floorNum)
+		}
+	})
+}
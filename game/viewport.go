@@ -0,0 +1,185 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// Each room takes a 3 character cell plus the passage to its right, and a
+	// line plus the passage line below it.
+	mapCellWidth  = 4
+	mapCellHeight = 2
+
+	// minSidePanelWidth is the room left for the camera and stats panels when
+	// the map is too big for the screen.
+	minSidePanelWidth = 36
+)
+
+// mapWindow is the part of the floor shown on screen, in rooms. x1 and y1 are
+// exclusive.
+type mapWindow struct {
+	x0, y0, x1, y1 int
+}
+
+// cameraWindow returns the cols x rows window of a w x h floor centered on
+// the player, shifted so it never goes past the edges of the floor.
+func cameraWindow(w, h, cols, rows, px, py int) mapWindow {
+	cols, rows = min(cols, w), min(rows, h)
+	x0 := max(0, min(px-cols/2, w-cols))
+	y0 := max(0, min(py-rows/2, h-rows))
+	return mapWindow{x0: x0, y0: y0, x1: x0 + cols, y1: y0 + rows}
+}
+
+// renderMapViewport draws as much of the current floor as fits in maxWidth x
+// maxHeight characters. Bigger floors scroll to keep focus in view and show
+// arrows on the sides where more of the floor is hidden. A size of zero means
+// there is no limit.
+func (m model) renderMapViewport(maxWidth, maxHeight int, focus image.Point) string {
+	worldMap := m.floors[m.currentFloor].worldMap
+	h, w := len(worldMap), len(worldMap[0])
+
+	v := m.mapViewport(maxWidth, maxHeight, focus)
+	win := v.win
+	if v.padX == 0 {
+		return m.renderMapWindow(win)
+	}
+
+	lines := strings.Split(m.renderMapWindow(win), "\n")
+	gridWidth := lipgloss.Width(lines[0])
+	middle := len(lines) / 2
+	for i, line := range lines {
+		left, right := " ", " "
+		if i == middle && win.x0 > 0 {
+			left = m.styles.Help.Render("◀")
+		}
+		if i == middle && win.x1 < w {
+			right = m.styles.Help.Render("▶")
+		}
+		lines[i] = left + line + right
+	}
+
+	indicator := func(show bool, arrow string) string {
+		if !show {
+			return ""
+		}
+		return lipgloss.PlaceHorizontal(gridWidth+2, lipgloss.Center, m.styles.Help.Render(arrow))
+	}
+	top := indicator(win.y0 > 0, "▲")
+	bottom := indicator(win.y1 < h, "▼")
+	if v.padY > 0 {
+		lines = append([]string{top}, append(lines, bottom)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// viewport is the part of the floor renderMapViewport shows. padX and padY
+// are the columns and lines its edge indicators add before the rooms.
+type viewport struct {
+	win        mapWindow
+	padX, padY int
+}
+
+func (m model) mapViewport(maxWidth, maxHeight int, focus image.Point) viewport {
+	worldMap := m.floors[m.currentFloor].worldMap
+	h, w := len(worldMap), len(worldMap[0])
+
+	fitsWidth := maxWidth <= 0 || w*mapCellWidth-1 <= maxWidth
+	fitsHeight := maxHeight <= 0 || h*mapCellHeight-1 <= maxHeight
+	if fitsWidth && fitsHeight {
+		return viewport{win: mapWindow{x1: w, y1: h}}
+	}
+
+	// Keep one column and one line on each side for the edge indicators.
+	v := viewport{padX: 1}
+	cols, rows := w, h
+	if !fitsWidth {
+		cols = max(1, (maxWidth-2+1)/mapCellWidth)
+	}
+	if !fitsHeight {
+		rows = max(1, (maxHeight-2+1)/mapCellHeight)
+		v.padY = 1
+	}
+	v.win = cameraWindow(w, h, cols, rows, focus.X, focus.Y)
+	return v
+}
+
+// roomAt returns the room drawn at column x, line y of the rendered
+// viewport, if any.
+func (v viewport) roomAt(x, y int) (image.Point, bool) {
+	x, y = x-v.padX, y-v.padY
+	if x < 0 || y < 0 || x%mapCellWidth == mapCellWidth-1 || y%mapCellHeight != 0 {
+		return image.Point{}, false
+	}
+	p := image.Point{X: v.win.x0 + x/mapCellWidth, Y: v.win.y0 + y/mapCellHeight}
+	if p.X >= v.win.x1 || p.Y >= v.win.y1 {
+		return image.Point{}, false
+	}
+	return p, true
+}
+
+// renderMapWindow draws the rooms inside win and the passages between them.
+func (m model) renderMapWindow(win mapWindow) string {
+	currentMap := m.floors[m.currentFloor].worldMap
+
+	var mapRows []string
+	for y := win.y0; y < win.y1; y++ {
+		var mapRow, linkRow strings.Builder
+		for x := win.x0; x < win.x1; x++ {
+			room := currentMap[y][x]
+			cell, style := "   ", m.styles.Room
+			if x == m.playerMapX && y == m.playerMapY {
+				cell, style = "[@]", m.styles.Player.UnsetString()
+			} else if room != nil && !room.Hidden && room.Visited && m.floors[m.currentFloor].wandererAt(x, y) {
+				cell, style = "[M]", m.styles.Room.Inherit(m.styles.Wanderer)
+			} else if room != nil && !room.Hidden && room.Seen {
+				symbol := "?"
+				if room.Marker != "" {
+					symbol = room.Marker
+				} else if room.Visited && room.Switch && !room.SwitchOn {
+					symbol = "s"
+				} else if room.Visited {
+					symbol = room.getRoomSymbol()
+				}
+				cell = fmt.Sprintf("[%s]", symbol)
+
+				// Unvisited rooms are plain [?]: their style must not give away
+				// what is inside. Secret rooms stand out as soon as they are
+				// found.
+				style = m.styles.Room
+				if room.Marker != "" || room.Note != "" {
+					style = style.Inherit(m.styles.Marked)
+				} else if room.Secret {
+					style = style.Inherit(m.styles.RoomSecret)
+				} else if room.Visited {
+					if room.Type == Boss {
+						style = style.Inherit(m.styles.RoomBoss)
+					} else if room.Type == Tresure || room.Type == Shop || room.Type == StairsUp || room.Type == Teleporter || room.Type == Pit || (room.Switch && !room.SwitchOn) {
+						style = style.Inherit(m.styles.RoomSpecial)
+					}
+				}
+			}
+			if m.showFullMap && x == m.mapCursor.X && y == m.mapCursor.Y {
+				style = style.Reverse(true)
+			}
+			mapRow.WriteString(style.Render(cell))
+
+			if x < win.x1-1 {
+				mapRow.WriteString(m.renderPassage(currentMap, x, y, East))
+			}
+			linkRow.WriteString(" " + m.renderPassage(currentMap, x, y, South) + " ")
+			if x < win.x1-1 {
+				linkRow.WriteString(" ")
+			}
+		}
+		mapRows = append(mapRows, mapRow.String())
+		if y < win.y1-1 {
+			mapRows = append(mapRows, linkRow.String())
+		}
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Center, mapRows...)
+}
@@ -0,0 +1,147 @@
+package game
+
+import (
+	"image"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+const (
+	// wandererChaseRange is how many rooms away a group notices the player.
+	wandererChaseRange = 3
+	// wandererSpawnDistance keeps new groups away from the start room.
+	wandererSpawnDistance = 2
+)
+
+// wanderer is a group of monsters roaming the floor. It moves one room per
+// turn of the floor clock and fights the player when they share a room.
+type wanderer struct {
+	pos     image.Point
+	enemies []string
+}
+
+// placeWanderers spawns up to count groups in the empty rooms farthest from
+// start.
+func placeWanderers(rng *rand.Rand, f *floor, start image.Point, count int, pool []string) {
+	distances := roomDistances(f.worldMap, start)
+	var spots []image.Point
+	for _, coord := range roomCoords(f.worldMap) {
+		r := f.worldMap[coord.Y][coord.X]
+		if d, ok := distances[coord]; ok && d >= wandererSpawnDistance && r.Type == Empty && !r.Hidden {
+			spots = append(spots, coord)
+		}
+	}
+	rng.Shuffle(len(spots), func(i, j int) { spots[i], spots[j] = spots[j], spots[i] })
+	sort.SliceStable(spots, func(i, j int) bool { return distances[spots[i]] > distances[spots[j]] })
+
+	for _, spot := range spots[:min(count, len(spots))] {
+		enemies := make([]string, 1+rng.Intn(2))
+		for i := range enemies {
+			enemies[i] = pool[rng.Intn(len(pool))]
+		}
+		f.wanderers = append(f.wanderers, &wanderer{pos: spot, enemies: enemies})
+	}
+}
+
+// wandererCanEnter reports whether monsters walk from a through the exit
+// towards dir. They stay out of locked doors, secret rooms and boss rooms.
+func wandererCanEnter(worldMap [][]*room, from image.Point, dir direction) bool {
+	exit := worldMap[from.Y][from.X].Exits[dir]
+	if exit == nil || exit.Locked {
+		return false
+	}
+	next := from.Add(cardinalDirections[dir])
+	r := worldMap[next.Y][next.X]
+	return !r.Hidden && r.Type != Boss
+}
+
+// roomDistances is the number of steps from start to every room monsters
+// can reach.
+func roomDistances(worldMap [][]*room, start image.Point) map[image.Point]int {
+	distances := map[image.Point]int{start: 0}
+	queue := []image.Point{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for dir := range cardinalDirections {
+			if !wandererCanEnter(worldMap, current, direction(dir)) {
+				continue
+			}
+			next := current.Add(cardinalDirections[dir])
+			if _, ok := distances[next]; !ok {
+				distances[next] = distances[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return distances
+}
+
+// moveWanderers moves every group one room: towards the player when close,
+// somewhere at random otherwise.
+func (m model) moveWanderers() {
+	f := m.floors[m.currentFloor]
+	player := image.Point{X: m.playerMapX, Y: m.playerMapY}
+	distances := roomDistances(f.worldMap, player)
+
+	for _, w := range f.wanderers {
+		var moves []image.Point
+		for dir := range cardinalDirections {
+			if wandererCanEnter(f.worldMap, w.pos, direction(dir)) {
+				moves = append(moves, w.pos.Add(cardinalDirections[dir]))
+			}
+		}
+		if len(moves) == 0 {
+			continue
+		}
+
+		if d, ok := distances[w.pos]; ok && d <= wandererChaseRange {
+			for _, next := range moves {
+				if distances[next] == d-1 {
+					w.pos = next
+					break
+				}
+			}
+			continue
+		}
+		// Groups that are not chasing sometimes stay where they are.
+		if rand.Intn(3) > 0 {
+			w.pos = moves[rand.Intn(len(moves))]
+		}
+	}
+}
+
+// meetWanderers starts a fight with a group in the player's room, if any.
+// Groups that find the player resting strike first.
+func (m model) meetWanderers(resting bool) model {
+	f := &m.floors[m.currentFloor]
+	for i, w := range f.wanderers {
+		if w.pos.X != m.playerMapX || w.pos.Y != m.playerMapY {
+			continue
+		}
+		f.wanderers = slices.Delete(f.wanderers, i, i+1)
+
+		enemies := make([]*Foe, 0, len(w.enemies))
+		for _, id := range w.enemies {
+			enemies = append(enemies, newFoe(id))
+		}
+		if resting {
+			m.message = "Wandering monsters find you while you rest!"
+		} else {
+			m.message = "A group of wandering monsters finds you!"
+		}
+		return m.startCombat(enemies, resting)
+	}
+	return m
+}
+
+// wandererAt reports whether a group stands in the room at x, y.
+func (f floor) wandererAt(x, y int) bool {
+	for _, w := range f.wanderers {
+		if w.pos.X == x && w.pos.Y == y {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -19,6 +20,7 @@ import (
 	"github.com/charmbracelet/wish/bubbletea"
 	"github.com/charmbracelet/wish/logging"
 	"github.com/joho/godotenv"
+	"github.com/muesli/termenv"
 )
 
 func init() {
@@ -39,18 +41,27 @@ func programHandler(s ssh.Session) (tea.Model, []tea.ProgramOption) {
 	// Log de información del terminal
 	log.Printf("PTY Info - Term: %s, Window: %dx%d", ptyReq.Term, ptyReq.Window.Width, ptyReq.Window.Height)
 
-	cmd := s.Command()
-	var initialState game.GameState
+	opts, err := parseGameCommand(s.Command())
+	if err != nil {
+		wish.Println(s, "Error:", err)
+		return nil, nil
+	}
+
+	if opts.StartState == game.StateEditor && !editorAllowed(accountID(s)) {
+		wish.Printf(s, "Error: the level editor is only available to the accounts in EDITOR_ACCOUNTS (yours is %s)\n", accountID(s))
+		return nil, nil
+	}
 
-	if len(cmd) > 0 && cmd[0] == "test-combat" {
+	switch opts.StartState {
+	case game.StateCombat:
 		log.Println("Starting test combat session...")
-		initialState = game.StateCombat
-	} else {
+	case game.StateEditor:
+		log.Printf("Starting level editor session for %s...", opts.Layout)
+	default:
 		log.Println("Starting normal game session...")
-		initialState = game.StateLoading
 	}
 
-	model, options := game.CreateTeaProgram(s, initialState)
+	model, options := game.CreateTeaProgram(s, opts)
 
 	// Agregar opciones adicionales para el terminal SSH
 	options = append(options,
@@ -68,15 +79,66 @@ func programHandler(s ssh.Session) (tea.Model, []tea.ProgramOption) {
 	return model, options
 }
 
+func teaHandler(s ssh.Session) *tea.Program {
+	model, options := programHandler(s)
+	if model == nil {
+		return nil
+	}
+	options = append(options, bubbletea.MakeOptions(s)...)
+
+	if recordingsEnabled() {
+		ptyReq, _, _ := s.Pty()
+		rec, err := newRecorder(s, accountID(s), ptyReq.Window.Width, ptyReq.Window.Height, ptyReq.Term)
+		if err != nil {
+			log.Printf("Failed to start session recording: %v", err)
+		} else {
+			options = append(options, tea.WithOutput(rec))
+			go func() {
+				<-s.Context().Done()
+				rec.Close()
+			}()
+		}
+	}
+
+	return tea.NewProgram(model, options...)
+}
+
+// shutdownTimeout es lo que esperamos a que cada servidor cierre sus
+// sesiones al apagar.
+const shutdownTimeout = 30 * time.Second
+
+// shutdownWithTimeout apaga un servidor con su propio plazo, para que uno
+// lento no deje sin tiempo a los demás.
+func shutdownWithTimeout(shutdown func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return shutdown(ctx)
+}
+
 func main() {
 	sshMode := flag.Bool("ssh", false, "Run in SSH mode")
-	startMode := flag.String("mode", "normal", "Starting mode: normal or test-combat")
+	startMode := flag.String("mode", "normal", "Starting mode: normal, test-combat or edit")
+	layout := flag.String("layout", "untitled", "Layout opened by -mode edit")
+	seed := flag.Int64("seed", 0, "Run seed for local mode (random if not set)")
+	checkFloors := flag.Int64("check-floors", 0, "Validate the floors generated by N seeds and exit")
+	checkDepth := flag.Int("check-depth", 12, "Number of floors per seed checked by -check-floors")
 	flag.Parse()
 
+	sessions = newSessionRegistry(envInt("MAX_SESSIONS"), envInt("MAX_SESSIONS_PER_ACCOUNT"))
+
 	if err := game.LoadGameData(); err != nil {
 		log.Fatalf("Failed to load game data: %v", err)
 	}
 
+	// Comprueba las invariantes de los pisos generados sin abrir el juego.
+	if *checkFloors > 0 {
+		if err := game.CheckFloors(*checkFloors, *checkDepth); err != nil {
+			log.Fatalf("Floor check failed: %v", err)
+		}
+		log.Printf("Floor check passed: %d seeds x %d floors", *checkFloors, *checkDepth)
+		return
+	}
+
 	if *sshMode {
 		log.Println("Running in SSH mode...")
 		host := os.Getenv("SSH_HOST")
@@ -92,9 +154,11 @@ func main() {
 			wish.WithAddress(fmt.Sprintf("%s:%s", host, port)),
 			wish.WithHostKeyPath("ssh_host_key"),
 			wish.WithMiddleware(
-				bubbletea.Middleware(programHandler),
-				logging.Middleware(),
+				bubbletea.MiddlewareWithProgramHandler(teaHandler, termenv.Ascii),
+				registryMiddleware(),
 				activeterm.Middleware(),
+				commandMiddleware(),
+				logging.Middleware(),
 			),
 			// Configuraciones adicionales para mejorar compatibilidad
 			wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
@@ -123,24 +187,56 @@ func main() {
 			}
 		}()
 
+		var telnet *telnetServer
+		if telnetPort := os.Getenv("TELNET_PORT"); telnetPort != "" {
+			telnet, err = startTelnetServer(fmt.Sprintf("%s:%s", host, telnetPort))
+			if err != nil {
+				log.Fatalf("failed to start telnet server: %s", err)
+			}
+			log.Printf("Starting telnet server on %s:%s (guest accounts only)", host, telnetPort)
+		}
+
+		var web *http.Server
+		if webPort := os.Getenv("WEB_PORT"); webPort != "" {
+			web, err = startWebServer(fmt.Sprintf("%s:%s", host, webPort))
+			if err != nil {
+				log.Fatalf("failed to start web server: %s", err)
+			}
+			log.Printf("Starting web terminal on http://%s:%s (guest accounts only)", host, webPort)
+		}
+
 		<-done
 		log.Println("Stopping SSH server...")
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer func() { cancel() }()
-		if err := s.Shutdown(ctx); err != nil {
+		if web != nil {
+			if err := shutdownWithTimeout(web.Shutdown); err != nil {
+				log.Println(err)
+			}
+		}
+		if telnet != nil {
+			if err := shutdownWithTimeout(telnet.Shutdown); err != nil {
+				log.Println(err)
+			}
+		}
+		if err := shutdownWithTimeout(s.Shutdown); err != nil {
 			log.Fatalln(err)
 		}
 	} else {
 		log.Println("Running in local terminal mode...")
-		var startState game.GameState
+		opts := game.Options{StartState: game.StateLoading, Seed: *seed}
 		switch *startMode {
 		case "test-combat":
-			startState = game.StateCombat
-		default:
-			startState = game.StateLoading
+			opts.StartState = game.StateCombat
+		case "edit":
+			opts.StartState = game.StateEditor
+			opts.Layout = *layout
 		}
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "seed" {
+				opts.HasSeed = true
+			}
+		})
 
-		initialModel, options := game.CreateTeaProgram(nil, startState)
+		initialModel, options := game.CreateTeaProgram(nil, opts)
 		p := tea.NewProgram(initialModel, options...)
 		if _, err := p.Run(); err != nil {
 			log.Fatalf("Error running program: %v", err)
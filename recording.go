@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const recordingExt = ".cast"
+
+func recordingsEnabled() bool {
+	switch strings.ToLower(os.Getenv("RECORD_SESSIONS")) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+func recordingsDir() string {
+	if dir := os.Getenv("RECORDINGS_DIR"); dir != "" {
+		return dir
+	}
+	return "recordings"
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// recorder copia todo lo que se escribe en la terminal del jugador a un
+// archivo asciicast v2, con el tiempo relativo de cada escritura.
+type recorder struct {
+	out   io.Writer
+	mu    sync.Mutex
+	file  *os.File
+	enc   *json.Encoder
+	start time.Time
+	// pending guarda el final de una escritura que corta un carácter UTF-8
+	// a medias, para grabarlo entero con la siguiente.
+	pending []byte
+}
+
+func newRecorder(out io.Writer, account string, width, height int, term string) (*recorder, error) {
+	dir := filepath.Join(recordingsDir(), account)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	name := fmt.Sprintf("%d%s", start.UnixNano(), recordingExt)
+	file, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	enc := json.NewEncoder(file)
+	header := castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Env:       map[string]string{"TERM": term},
+	}
+	if err := enc.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &recorder{out: out, file: file, enc: enc, start: start}, nil
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	n, err := r.out.Write(p)
+	if n > 0 {
+		r.mu.Lock()
+		if r.file != nil {
+			data := append(r.pending, p[:n]...)
+			cut := len(data) - incompleteTail(data)
+			r.pending = append([]byte(nil), data[cut:]...)
+			if cut > 0 {
+				elapsed := time.Since(r.start).Seconds()
+				if encErr := r.enc.Encode([]any{elapsed, "o", string(data[:cut])}); encErr != nil {
+					r.file.Close()
+					r.file = nil
+				}
+			}
+		}
+		r.mu.Unlock()
+	}
+	return n, err
+}
+
+// incompleteTail devuelve cuántos bytes del final de p son el principio de
+// un carácter UTF-8 que todavía no ha llegado entero.
+func incompleteTail(p []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		if utf8.RuneStart(p[len(p)-i]) {
+			if utf8.FullRune(p[len(p)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+type recordingInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+func listRecordings(account string) ([]recordingInfo, error) {
+	entries, err := os.ReadDir(filepath.Join(recordingsDir(), account))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recordings []recordingInfo
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != recordingExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		recordings = append(recordings, recordingInfo{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].ModTime.After(recordings[j].ModTime)
+	})
+	return recordings, nil
+}
+
+func openRecording(account, name string) (*os.File, error) {
+	if name != filepath.Base(name) || filepath.Ext(name) != recordingExt {
+		return nil, fmt.Errorf("invalid recording name %q", name)
+	}
+	return os.Open(filepath.Join(recordingsDir(), account, name))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecorderKeepsSplitRunes(t *testing.T) {
+	t.Setenv("RECORDINGS_DIR", t.TempDir())
+	rec, err := newRecorder(io.Discard, "test", 80, 24, "xterm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "▲" son tres bytes; la terminal puede recibirlos en escrituras distintas.
+	glyph := []byte("▲")
+	for _, chunk := range [][]byte{[]byte("a" + string(glyph[:1])), glyph[1:2], append(glyph[2:], 'b')} {
+		if _, err := rec.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := rec.file.Name()
+	rec.Close()
+
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // cabecera
+	var output strings.Builder
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		output.WriteString(event[2].(string))
+	}
+	if got := output.String(); got != "a▲b" {
+		t.Errorf("recorded %q, want %q", got, "a▲b")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+var errServerFull = errors.New("the server is full, try again later")
+var errAccountBusy = errors.New("this account already has too many open sessions")
+
+type sessionInfo struct {
+	ID        uint64
+	Account   string
+	Transport string
+	Remote    string
+	Started   time.Time
+}
+
+// sessionRegistry lleva la cuenta de las partidas abiertas en todos los
+// transportes (ssh, telnet y web) para aplicar los mismos límites.
+type sessionRegistry struct {
+	mu            sync.Mutex
+	nextID        uint64
+	sessions      map[uint64]sessionInfo
+	maxTotal      int
+	maxPerAccount int
+}
+
+func newSessionRegistry(maxTotal, maxPerAccount int) *sessionRegistry {
+	return &sessionRegistry{
+		sessions:      make(map[uint64]sessionInfo),
+		maxTotal:      maxTotal,
+		maxPerAccount: maxPerAccount,
+	}
+}
+
+// sessions se crea en main, después de cargar .env, para que los límites
+// definidos allí se apliquen.
+var sessions *sessionRegistry
+
+func envInt(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q: %v", key, value, err)
+		return 0
+	}
+	return n
+}
+
+// acquire registra una partida nueva. El release devuelto debe llamarse al
+// terminar la sesión. Un límite en 0 significa sin límite; los invitados no
+// cuentan para el límite por cuenta porque comparten la misma cuenta.
+func (r *sessionRegistry) acquire(account, transport, remote string) (func(), error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxTotal > 0 && len(r.sessions) >= r.maxTotal {
+		return nil, errServerFull
+	}
+	if r.maxPerAccount > 0 && account != guestAccount {
+		open := 0
+		for _, info := range r.sessions {
+			if info.Account == account {
+				open++
+			}
+		}
+		if open >= r.maxPerAccount {
+			return nil, errAccountBusy
+		}
+	}
+
+	r.nextID++
+	id := r.nextID
+	r.sessions[id] = sessionInfo{
+		ID:        id,
+		Account:   account,
+		Transport: transport,
+		Remote:    remote,
+		Started:   time.Now(),
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.sessions, id)
+			r.mu.Unlock()
+		})
+	}, nil
+}
+
+func registryMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			release, err := sessions.acquire(accountID(s), "ssh", s.RemoteAddr().String())
+			if err != nil {
+				wish.Fatalln(s, "Error:", err)
+				return
+			}
+			defer release()
+			next(s)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"ssh-dungeon-crawler/game"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// connSet guarda las conexiones abiertas de un servidor para cerrarlas al
+// apagarlo; si no, Shutdown esperaría a que cada jugador saliera por su
+// cuenta.
+type connSet struct {
+	mu     sync.Mutex
+	conns  map[io.Closer]struct{}
+	closed bool
+}
+
+// add registra c. Devuelve false si el servidor ya se está apagando.
+func (s *connSet) add(c io.Closer) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[io.Closer]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *connSet) remove(c io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, c)
+}
+
+// closeAll cierra las conexiones abiertas y rechaza las siguientes.
+func (s *connSet) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// remoteTerminal es una terminal que no llega por SSH (telnet, navegador).
+// windows debe cerrarse cuando el cliente se desconecta.
+type remoteTerminal struct {
+	input   io.Reader
+	output  io.Writer
+	term    string
+	window  tea.WindowSizeMsg
+	windows <-chan tea.WindowSizeMsg
+}
+
+type remoteEnviron []string
+
+func (e remoteEnviron) Environ() []string { return e }
+
+func (e remoteEnviron) Getenv(key string) string {
+	for _, v := range e {
+		if strings.HasPrefix(v, key+"=") {
+			return v[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+func runRemoteGame(rt remoteTerminal) error {
+	renderer := lipgloss.NewRenderer(rt.output,
+		termenv.WithEnvironment(remoteEnviron{"TERM=" + rt.term}),
+		termenv.WithUnsafe(),
+		termenv.WithColorCache(true),
+	)
+
+	model, options := game.CreateTeaProgramWithRenderer(renderer, game.Options{StartState: game.StateLoading})
+	options = append(options,
+		tea.WithInput(rt.input),
+		tea.WithOutput(rt.output),
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+	p := tea.NewProgram(model, options...)
+
+	go func() {
+		p.Send(rt.window)
+		for w := range rt.windows {
+			p.Send(w)
+		}
+		// El cliente cerró la conexión.
+		p.Quit()
+	}()
+
+	if _, err := p.Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		return err
+	}
+	return nil
+}
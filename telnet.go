@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	telnetIAC  = 255
+	telnetDONT = 254
+	telnetDO   = 253
+	telnetWONT = 252
+	telnetWILL = 251
+	telnetSB   = 250
+	telnetSE   = 240
+
+	telnetOptEcho = 1
+	telnetOptSGA  = 3
+	telnetOptTTYP = 24
+	telnetOptNAWS = 31
+
+	telnetTTYPIs   = 0
+	telnetTTYPSend = 1
+)
+
+// Tiempo máximo que esperamos al cliente para que informe el tamaño de la
+// ventana y el tipo de terminal antes de arrancar el juego.
+const telnetNegotiationTimeout = 2 * time.Second
+
+type telnetServer struct {
+	listener net.Listener
+	conns    connSet
+	wg       sync.WaitGroup
+}
+
+func startTelnetServer(addr string) (*telnetServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &telnetServer{listener: listener}
+	go srv.serve()
+	return srv, nil
+}
+
+func (srv *telnetServer) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Telnet accept error: %v", err)
+			}
+			return
+		}
+
+		if !srv.conns.add(conn) {
+			conn.Close()
+			return
+		}
+		srv.wg.Add(1)
+		go func() {
+			defer srv.wg.Done()
+			defer srv.conns.remove(conn)
+			handleTelnet(conn)
+		}()
+	}
+}
+
+// Shutdown deja de aceptar conexiones, corta las partidas en curso y espera
+// a que terminen.
+func (srv *telnetServer) Shutdown(ctx context.Context) error {
+	err := srv.listener.Close()
+	srv.conns.closeAll()
+
+	done := make(chan struct{})
+	go func() {
+		srv.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// telnetSession separa los comandos de negociación del texto que escribe el
+// jugador. El texto limpio se entrega por input y los cambios de ventana
+// por windows.
+type telnetSession struct {
+	conn    net.Conn
+	input   *io.PipeReader
+	windows chan tea.WindowSizeMsg
+	terms   chan string
+
+	writeMu sync.Mutex
+}
+
+func newTelnetSession(conn net.Conn) *telnetSession {
+	pr, pw := io.Pipe()
+	ts := &telnetSession{
+		conn:    conn,
+		input:   pr,
+		windows: make(chan tea.WindowSizeMsg, 8),
+		terms:   make(chan string, 1),
+	}
+	go ts.readLoop(pw)
+	return ts
+}
+
+func (ts *telnetSession) negotiate() error {
+	_, err := ts.writeRaw([]byte{
+		telnetIAC, telnetWILL, telnetOptEcho,
+		telnetIAC, telnetWILL, telnetOptSGA,
+		telnetIAC, telnetDO, telnetOptSGA,
+		telnetIAC, telnetDO, telnetOptNAWS,
+		telnetIAC, telnetDO, telnetOptTTYP,
+	})
+	return err
+}
+
+func (ts *telnetSession) writeRaw(p []byte) (int, error) {
+	ts.writeMu.Lock()
+	defer ts.writeMu.Unlock()
+	return ts.conn.Write(p)
+}
+
+// Write escapa los bytes IAC de la salida del juego.
+func (ts *telnetSession) Write(p []byte) (int, error) {
+	escaped := p
+	if bytes.IndexByte(p, telnetIAC) >= 0 {
+		escaped = make([]byte, 0, len(p)+8)
+		for _, b := range p {
+			if b == telnetIAC {
+				escaped = append(escaped, telnetIAC)
+			}
+			escaped = append(escaped, b)
+		}
+	}
+	if _, err := ts.writeRaw(escaped); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (ts *telnetSession) readLoop(input *io.PipeWriter) {
+	defer close(ts.windows)
+
+	r := bufio.NewReader(ts.conn)
+	var lastCR bool
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			input.CloseWithError(err)
+			return
+		}
+
+		if b != telnetIAC {
+			// Enter llega como CR NUL o CR LF; el juego sólo necesita el CR.
+			if lastCR && (b == 0 || b == '\n') {
+				lastCR = false
+				continue
+			}
+			lastCR = b == '\r'
+			if _, err := input.Write([]byte{b}); err != nil {
+				return
+			}
+			continue
+		}
+
+		cmd, err := r.ReadByte()
+		if err != nil {
+			input.CloseWithError(err)
+			return
+		}
+
+		switch cmd {
+		case telnetIAC:
+			if _, err := input.Write([]byte{telnetIAC}); err != nil {
+				return
+			}
+		case telnetWILL:
+			opt, _ := r.ReadByte()
+			if opt == telnetOptTTYP {
+				ts.writeRaw([]byte{telnetIAC, telnetSB, telnetOptTTYP, telnetTTYPSend, telnetIAC, telnetSE})
+			}
+		case telnetWONT, telnetDO, telnetDONT:
+			r.ReadByte()
+		case telnetSB:
+			ts.readSubnegotiation(r)
+		}
+	}
+}
+
+func (ts *telnetSession) readSubnegotiation(r *bufio.Reader) {
+	var data []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		if b == telnetIAC {
+			next, err := r.ReadByte()
+			if err != nil || next == telnetSE {
+				break
+			}
+			b = next
+		}
+		data = append(data, b)
+	}
+
+	if len(data) == 0 {
+		return
+	}
+
+	switch data[0] {
+	case telnetOptNAWS:
+		if len(data) < 5 {
+			return
+		}
+		width := int(data[1])<<8 | int(data[2])
+		height := int(data[3])<<8 | int(data[4])
+		select {
+		case ts.windows <- tea.WindowSizeMsg{Width: width, Height: height}:
+		default:
+		}
+	case telnetOptTTYP:
+		if len(data) < 2 || data[1] != telnetTTYPIs {
+			return
+		}
+		select {
+		case ts.terms <- strings.ToLower(string(data[2:])):
+		default:
+		}
+	}
+}
+
+func handleTelnet(conn net.Conn) {
+	defer conn.Close()
+	log.Printf("Telnet connect %s (account: %s)", conn.RemoteAddr(), guestAccount)
+
+	release, err := sessions.acquire(guestAccount, "telnet", conn.RemoteAddr().String())
+	if err != nil {
+		conn.Write([]byte("Error: " + err.Error() + "\r\n"))
+		return
+	}
+	defer release()
+
+	ts := newTelnetSession(conn)
+	if err := ts.negotiate(); err != nil {
+		log.Printf("Telnet negotiation failed for %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+
+	term := "xterm"
+	window := tea.WindowSizeMsg{Width: 80, Height: 24}
+	gotTerm, gotWindow := false, false
+	timeout := time.After(telnetNegotiationTimeout)
+	for !gotTerm || !gotWindow {
+		select {
+		case t := <-ts.terms:
+			term, gotTerm = t, true
+		case w, ok := <-ts.windows:
+			if !ok {
+				return
+			}
+			window, gotWindow = w, true
+		case <-timeout:
+			gotTerm, gotWindow = true, true
+		}
+	}
+	log.Printf("Telnet terminal - Term: %s, Window: %dx%d", term, window.Width, window.Height)
+
+	err = runRemoteGame(remoteTerminal{
+		input:   ts.input,
+		output:  ts,
+		term:    term,
+		window:  window,
+		windows: ts.windows,
+	})
+	if err != nil {
+		log.Printf("Telnet session for %s exited with error: %v", conn.RemoteAddr(), err)
+	}
+	log.Printf("Telnet disconnect %s", conn.RemoteAddr())
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTelnetShutdownClosesSessions(t *testing.T) {
+	sessions = newSessionRegistry(0, 0)
+	srv, err := startTelnetServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", srv.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Sin respuesta a la negociación el juego arranca a los pocos segundos.
+	var output []byte
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for !bytes.Contains(output, []byte("Start Game")) {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("waiting for the menu: %v", err)
+		}
+		output = append(output, buf[:n]...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown with a player connected: %v", err)
+	}
+}
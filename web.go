@@ -0,0 +1,200 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gorilla/websocket"
+)
+
+//go:embed web/index.html
+var webIndex []byte
+
+// Tiempo máximo que esperamos el primer mensaje resize del navegador.
+const webResizeTimeout = 2 * time.Second
+
+// webControl es un mensaje de texto del navegador. Los mensajes binarios son
+// las teclas del jugador tal cual; la salida del juego se envía también como
+// mensajes binarios.
+type webControl struct {
+	Type string `json:"type"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+	Data string `json:"data"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+func newWebServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(webIndex)
+	})
+	// Las conexiones websocket quedan fuera del http.Server al secuestrarse,
+	// así que Shutdown no las cierra por su cuenta.
+	conns := &connSet{}
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(conns, w, r)
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	srv.RegisterOnShutdown(conns.closeAll)
+	return srv
+}
+
+func startWebServer(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := newWebServer(addr)
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Web server error: %v", err)
+		}
+	}()
+	return srv, nil
+}
+
+// webTerminal adapta una conexión websocket a los io.Reader/io.Writer que
+// espera el programa de Bubble Tea.
+type webTerminal struct {
+	conn    *websocket.Conn
+	input   *io.PipeReader
+	windows chan tea.WindowSizeMsg
+
+	writeMu sync.Mutex
+}
+
+func newWebTerminal(conn *websocket.Conn) *webTerminal {
+	pr, pw := io.Pipe()
+	wt := &webTerminal{
+		conn:    conn,
+		input:   pr,
+		windows: make(chan tea.WindowSizeMsg, 8),
+	}
+	go wt.readLoop(pw)
+	return wt
+}
+
+func (wt *webTerminal) Write(p []byte) (int, error) {
+	wt.writeMu.Lock()
+	defer wt.writeMu.Unlock()
+	if err := wt.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (wt *webTerminal) writeText(text string) {
+	wt.writeMu.Lock()
+	defer wt.writeMu.Unlock()
+	wt.conn.WriteMessage(websocket.BinaryMessage, []byte(text))
+}
+
+func (wt *webTerminal) readLoop(input *io.PipeWriter) {
+	defer close(wt.windows)
+
+	for {
+		msgType, data, err := wt.conn.ReadMessage()
+		if err != nil {
+			input.CloseWithError(err)
+			return
+		}
+
+		if msgType == websocket.BinaryMessage {
+			if _, err := input.Write(data); err != nil {
+				return
+			}
+			continue
+		}
+
+		var ctrl webControl
+		if err := json.Unmarshal(data, &ctrl); err != nil {
+			log.Printf("Ignoring invalid web control message: %v", err)
+			continue
+		}
+		switch ctrl.Type {
+		case "resize":
+			if ctrl.Cols <= 0 || ctrl.Rows <= 0 {
+				continue
+			}
+			select {
+			case wt.windows <- tea.WindowSizeMsg{Width: ctrl.Cols, Height: ctrl.Rows}:
+			default:
+			}
+		case "input":
+			if _, err := input.Write([]byte(ctrl.Data)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func handleWebSocket(conns *connSet, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Web upgrade failed for %s: %v", r.RemoteAddr, err)
+		return
+	}
+	defer conn.Close()
+	if !conns.add(conn) {
+		return
+	}
+	defer conns.remove(conn)
+	log.Printf("Web connect %s (account: %s)", r.RemoteAddr, guestAccount)
+
+	wt := newWebTerminal(conn)
+
+	release, err := sessions.acquire(guestAccount, "web", r.RemoteAddr)
+	if err != nil {
+		wt.writeText("Error: " + err.Error() + "\r\n")
+		return
+	}
+	defer release()
+
+	window := tea.WindowSizeMsg{Width: 80, Height: 24}
+	select {
+	case w, ok := <-wt.windows:
+		if !ok {
+			return
+		}
+		window = w
+	case <-time.After(webResizeTimeout):
+	}
+
+	err = runRemoteGame(remoteTerminal{
+		input:   wt.input,
+		output:  wt,
+		term:    "xterm-256color",
+		window:  window,
+		windows: wt.windows,
+	})
+	if err != nil {
+		log.Printf("Web session for %s exited with error: %v", r.RemoteAddr, err)
+	}
+
+	wt.writeMu.Lock()
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"),
+		time.Now().Add(time.Second))
+	wt.writeMu.Unlock()
+	log.Printf("Web disconnect %s", r.RemoteAddr)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWeb abre una sesión web contra el servidor de baseURL y la cierra al
+// terminar el test.
+func dialWeb(t *testing.T, baseURL string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(baseURL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// waitForOutput lee la salida de la terminal hasta que aparece want.
+func waitForOutput(t *testing.T, conn *websocket.Conn, want string) {
+	t.Helper()
+	var output strings.Builder
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for !strings.Contains(output.String(), want) {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v\noutput so far: %q", want, err, output.String())
+		}
+		output.Write(data)
+	}
+}
+
+func TestWebSocketSession(t *testing.T) {
+	sessions = newSessionRegistry(1, 0)
+	srv := httptest.NewServer(newWebServer("").Handler)
+	defer srv.Close()
+
+	conn := dialWeb(t, srv.URL)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"resize","cols":100,"rows":30}`)); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, conn, "Start Game")
+
+	// La flecha abajo llega como bytes de teclado y mueve el cursor del menú.
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("\x1b[B")); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, conn, "> Exit")
+
+	// Con MAX_SESSIONS=1 una segunda sesión se rechaza mientras la primera sigue abierta.
+	second := dialWeb(t, srv.URL)
+	waitForOutput(t, second, errServerFull.Error())
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("q")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	// Al salir la partida libera su plaza.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		release, err := sessions.acquire(guestAccount, "web", "test")
+		if err == nil {
+			release()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the session was not released: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestWebShutdownClosesSessions(t *testing.T) {
+	sessions = newSessionRegistry(0, 0)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newWebServer("")
+	go srv.Serve(listener)
+
+	conn := dialWeb(t, "http://"+listener.Addr().String())
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"resize","cols":100,"rows":30}`)); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, conn, "Start Game")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// La sesión secuestrada se cierra con el servidor.
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Fatal("the web session is still open after shutdown")
+			}
+			break
+		}
+	}
+}